@@ -0,0 +1,126 @@
+package run
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and timers, so that the time can be
+// controlled in tests. The runners and groups in the module get the clock via
+// ClockFromContext.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the interface of a *time.Timer created by a Clock
+type Timer interface {
+	// C returns the channel on which the time is delivered, it returns nil for
+	// a timer created by AfterFunc
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the Clock backed by package time
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+type clockKey struct{}
+
+// WithClock returns a copy of ctx carrying clock
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// ClockFromContext returns the clock carried by ctx, or SystemClock if there
+// is none
+func ClockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return SystemClock
+}
+
+// withDeadline is like context.WithDeadline, except that the deadline is
+// measured by clock
+func withDeadline(parent context.Context, clock Clock, deadline time.Time) (context.Context, context.CancelFunc) {
+	if clock == SystemClock {
+		return context.WithDeadline(parent, deadline)
+	}
+	c := &deadlineCtx{Context: parent, deadline: deadline, done: make(chan struct{})}
+	if parent.Done() != nil {
+		go func() {
+			select {
+			case <-parent.Done():
+				c.cancel(parent.Err())
+			case <-c.done:
+			}
+		}()
+	}
+	d := deadline.Sub(clock.Now())
+	if d <= 0 {
+		c.cancel(context.DeadlineExceeded)
+		return c, func() { c.cancel(context.Canceled) }
+	}
+	timer := clock.AfterFunc(d, func() { c.cancel(context.DeadlineExceeded) })
+	return c, func() {
+		timer.Stop()
+		c.cancel(context.Canceled)
+	}
+}
+
+// deadlineCtx is a context cancelled by a timer of a Clock. It has its own
+// done channel rather than embedding a cancellable context, so that the
+// contexts derived from it are cancelled with its own error, e.g.
+// context.DeadlineExceeded, rather than context.Canceled.
+type deadlineCtx struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *deadlineCtx) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+func (c *deadlineCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *deadlineCtx) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *deadlineCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
@@ -0,0 +1,56 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestWithTimeoutClock(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	errChan := make(chan error)
+	go func() {
+		errChan <- run.WithTimeout(run.Func(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}), time.Second).Run(ctx)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	err := <-errChan
+	var timeoutErr *run.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expect *run.TimeoutError got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect context.DeadlineExceeded got %v", err)
+	}
+}
+
+func TestWithTimeoutClockDerived(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	errChan := make(chan error)
+	go func() {
+		errChan <- run.WithTimeout(run.Func(func(ctx context.Context) error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}), time.Second).Run(ctx)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-errChan; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect context.DeadlineExceeded from a derived context got %v", err)
+	}
+}
@@ -4,56 +4,168 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync/atomic"
 )
 
-// Closer wraps a Runner into a Closer, whose Close method will cancel the
-// runner and wait for its exit and return its error
-func Closer(runner Runner) io.Closer {
-	return newCloser(runner, false)
+// ErrClosed is returned when the Closer is already closed
+var ErrClosed = errors.New("run.Closer: already closed")
+
+// Handle is the handle of a runner started by Start
+type Handle struct {
+	runner      Runner
+	parent      context.Context
+	cancel      context.CancelFunc
+	done        chan struct{}
+	err         error
+	onEarlyExit func(err error)
+	stopping    int32
+	closed      int32
 }
 
-// WaitCloser wraps a Runner into a Closer, whose Close method will wait for
-// the runner to exit and return its error
-func WaitCloser(runner Runner) io.Closer {
-	return newCloser(runner, true)
+// StartOption is used to specify an option for Start
+type StartOption func(*Handle)
+
+// OnEarlyExit specifies a function to be called with the error returned by
+// the runner, if the runner exits on its own, i.e. before it is stopped or
+// its parent context is cancelled
+func OnEarlyExit(f func(err error)) StartOption {
+	return func(h *Handle) {
+		h.onEarlyExit = f
+	}
 }
 
-// ErrClosed is returned when the Closer is already closed
-var ErrClosed = errors.New("run.Closer: already closed")
+// Start runs runner with a context derived from ctx, on the pool from
+// PoolFromContext or in a new goroutine if there is none, and returns its
+// handle
+func Start(ctx context.Context, runner Runner, options ...StartOption) *Handle {
+	runCtx, cancel := context.WithCancel(ctx)
+	h := &Handle{
+		runner: runner,
+		parent: ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(h)
+	}
+	if err := goFunc(runCtx, func() {
+		h.exit(runner.Run(runCtx))
+	}); err != nil {
+		h.exit(err)
+	}
+	return h
+}
+
+func (h *Handle) exit(err error) {
+	h.err = err
+	early := atomic.LoadInt32(&h.stopping) == 0 && h.parent.Err() == nil
+	close(h.done)
+	h.cancel()
+	if early && h.onEarlyExit != nil {
+		h.onEarlyExit(err)
+	}
+}
+
+// Name returns the name of the runner
+func (h *Handle) Name() string {
+	return Name(h.runner)
+}
 
-type closer struct {
-	cancel  func()
-	errChan chan error
-	wait    bool
+// Done returns a channel that is closed when the runner exits
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
 }
 
-func newCloser(runner Runner, wait bool) io.Closer {
-	ctx, cancel := context.WithCancel(context.Background())
-	c := &closer{
-		cancel:  cancel,
-		errChan: make(chan error, 1),
-		wait:    wait,
+// Err returns nil if the runner has not exited yet, otherwise the error
+// returned by the runner
+func (h *Handle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
 	}
-	go func() {
-		if err := runner.Run(ctx); err != nil {
-			c.errChan <- err
+}
+
+// Wait waits for the runner to exit and returns its error
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Cancel cancels the context of the runner without waiting for its exit
+func (h *Handle) Cancel() {
+	atomic.StoreInt32(&h.stopping, 1)
+	h.cancel()
+}
+
+// Ready returns the readiness channel of the runner if it is a Readier,
+// otherwise a closed channel. The channel may never be closed if the runner
+// exits before being ready, so Done should also be selected.
+func (h *Handle) Ready() <-chan struct{} {
+	if r, ok := h.runner.(Readier); ok {
+		return r.Ready()
+	}
+	return closedChan
+}
+
+// Stop stops the runner and waits for its exit, and returns its error. If the
+// runner is a Stopper, its Stop method is called first to drain gracefully,
+// then the context of the runner is cancelled. If ctx is done before the
+// runner exits, Stop returns ctx.Err() and leaves the runner exiting in the
+// background.
+func (h *Handle) Stop(ctx context.Context) error {
+	atomic.StoreInt32(&h.stopping, 1)
+	var drainErr error
+	if s, ok := h.runner.(Stopper); ok {
+		drainErr = s.Stop(ctx)
+	}
+	h.cancel()
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		select {
+		case <-h.done:
+		default:
+			return ctx.Err()
 		}
-		// close errChan so that subsequent calls to Close will not block
-		close(c.errChan)
-	}()
-	return c
+	}
+	if h.err == nil {
+		return drainErr
+	}
+	return h.err
 }
 
-// Close cancels the internal goroutine for runner, waits for its exit and
-// returns the error returned by the runner. Subsequent calls to Close will
-// return ErrClosed
-func (c *closer) Close() error {
-	if !c.wait {
-		c.cancel()
+// Close stops the runner without a deadline and returns its error, it
+// satisfies io.Closer. Subsequent calls to Close will return ErrClosed.
+func (h *Handle) Close() error {
+	if !atomic.CompareAndSwapInt32(&h.closed, 0, 1) {
+		return ErrClosed
 	}
-	err, ok := <-c.errChan
-	if !ok {
+	return h.Stop(context.Background())
+}
+
+// Closer wraps a Runner into a Closer, whose Close method will cancel the
+// runner and wait for its exit and return its error
+func Closer(runner Runner) io.Closer {
+	return Start(context.Background(), runner)
+}
+
+// WaitCloser wraps a Runner into a Closer, whose Close method will wait for
+// the runner to exit and return its error
+func WaitCloser(runner Runner) io.Closer {
+	return waitCloser{Start(context.Background(), runner)}
+}
+
+type waitCloser struct {
+	*Handle
+}
+
+// Close waits for the runner to exit and returns its error. Subsequent calls
+// to Close will return ErrClosed
+func (c waitCloser) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
 		return ErrClosed
 	}
-	return err
+	return c.Wait()
 }
@@ -0,0 +1,159 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestStartStop(t *testing.T) {
+	defer runtest.Check(t)()
+
+	errRun := errors.New("err run")
+	started := make(chan struct{})
+	h := run.Start(context.Background(), run.Func(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return errRun
+	}), run.OnEarlyExit(func(err error) {
+		t.Errorf("unexpected early exit with error %v", err)
+	}))
+	<-started
+	if err := h.Err(); err != nil {
+		t.Fatalf("expect no error before exit but got %v", err)
+	}
+	if err := h.Stop(context.Background()); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	<-h.Done()
+	if err := h.Err(); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+}
+
+func TestStartStopTimeout(t *testing.T) {
+	defer runtest.Check(t)()
+
+	release := make(chan struct{})
+	h := run.Start(context.Background(), run.Func(func(context.Context) error {
+		<-release // ignore cancellation
+		return nil
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Stop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect error %v got %v", context.DeadlineExceeded, err)
+	}
+	close(release)
+	if err := h.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartEarlyExit(t *testing.T) {
+	defer runtest.Check(t)()
+
+	errRun := errors.New("err run")
+	exited := make(chan error, 1)
+	h := run.Start(context.Background(), &runtest.FakeRunner{Err: errRun}, run.OnEarlyExit(func(err error) {
+		exited <- err
+	}))
+	if err := <-exited; err != errRun {
+		t.Fatalf("expect early exit with error %v got %v", errRun, err)
+	}
+	if err := h.Wait(); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+
+	// the cancellation of the parent context is not an early exit
+	ctx, cancel := context.WithCancel(context.Background())
+	h = run.Start(ctx, &runtest.FakeRunner{Block: true}, run.OnEarlyExit(func(err error) {
+		t.Errorf("unexpected early exit with error %v", err)
+	}))
+	cancel()
+	<-h.Done()
+}
+
+func TestCloser(t *testing.T) {
+	defer runtest.Check(t)()
+
+	testcases := []struct {
+		name   string
+		closer func(run.Runner) io.Closer
+		runner run.Runner
+	}{
+		{
+			name:   "closer",
+			closer: run.Closer,
+			runner: run.Func(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			}),
+		},
+		{
+			name:   "wait closer",
+			closer: run.WaitCloser,
+			runner: &runtest.FakeRunner{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.closer(tc.runner)
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Close(); err != run.ErrClosed {
+				t.Fatalf("expect error %v got %v", run.ErrClosed, err)
+			}
+		})
+	}
+}
+
+func TestStartStopper(t *testing.T) {
+	defer runtest.Check(t)()
+
+	s := &drainRunner{ready: make(chan struct{}), drain: make(chan struct{})}
+	h := run.Start(context.Background(), s)
+	<-h.Ready()
+	if err := h.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !s.drained {
+		t.Fatal("expect the runner drained before cancellation")
+	}
+
+	// a runner not being a Readier is always ready
+	h = run.Start(context.Background(), &runtest.FakeRunner{Block: true})
+	<-h.Ready()
+	h.Cancel()
+	<-h.Done()
+}
+
+type drainRunner struct {
+	ready   chan struct{}
+	drain   chan struct{}
+	drained bool
+}
+
+func (s *drainRunner) Ready() <-chan struct{} { return s.ready }
+
+func (s *drainRunner) Stop(ctx context.Context) error {
+	close(s.drain)
+	return nil
+}
+
+func (s *drainRunner) Run(ctx context.Context) error {
+	close(s.ready)
+	select {
+	case <-s.drain:
+		s.drained = true
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Config is the configuration of the supervisor
+type Config struct {
+	Socket   string           `json:"socket"`   // path of the control socket
+	Programs []*ProgramConfig `json:"programs"` // programs to supervise
+}
+
+// ProgramConfig is the configuration of a supervised program
+type ProgramConfig struct {
+	Name      string            `json:"name"`
+	Command   []string          `json:"command"`    // the command and its arguments
+	Dir       string            `json:"dir"`        // working directory
+	Env       map[string]string `json:"env"`        // environment added to the current one
+	Restart   RestartPolicy     `json:"restart"`    // always, on-failure or never, default to on-failure
+	Backoff   Duration          `json:"backoff"`    // delay before a restart, default to 1s
+	Grace     Duration          `json:"grace"`      // grace period between SIGTERM and SIGKILL, default to 10s
+	DependsOn []string          `json:"depends_on"` // programs started before and stopped after it
+}
+
+// RestartPolicy decides whether a program is restarted after it exits
+type RestartPolicy string
+
+// RestartPolicy constants
+const (
+	RestartAlways    RestartPolicy = "always"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartNever     RestartPolicy = "never"
+)
+
+// Duration is a time.Duration represented as a string like "1s" in JSON
+type Duration time.Duration
+
+// UnmarshalJSON satisfies json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON satisfies json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads the config file and validates it
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var config Config
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// validate checks the config, fills the defaults and sorts the programs so
+// that each program comes after its dependencies
+func (c *Config) validate() error {
+	if c.Socket == "" {
+		c.Socket = "supervisor.sock"
+	}
+	if len(c.Programs) == 0 {
+		return errors.New("no programs")
+	}
+	byName := make(map[string]*ProgramConfig)
+	for _, p := range c.Programs {
+		if p.Name == "" {
+			return errors.New("program without a name")
+		}
+		if _, ok := byName[p.Name]; ok {
+			return fmt.Errorf("duplicate program %q", p.Name)
+		}
+		if len(p.Command) == 0 {
+			return fmt.Errorf("program %q without a command", p.Name)
+		}
+		switch p.Restart {
+		case "":
+			p.Restart = RestartOnFailure
+		case RestartAlways, RestartOnFailure, RestartNever:
+		default:
+			return fmt.Errorf("program %q with an invalid restart policy %q", p.Name, p.Restart)
+		}
+		if p.Backoff <= 0 {
+			p.Backoff = Duration(time.Second)
+		}
+		if p.Grace <= 0 {
+			p.Grace = Duration(10 * time.Second)
+		}
+		byName[p.Name] = p
+	}
+	for _, p := range c.Programs {
+		for _, dep := range p.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("program %q depends on an unknown program %q", p.Name, dep)
+			}
+		}
+	}
+
+	// topological sort by depth-first search
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int)
+	sorted := make([]*ProgramConfig, 0, len(c.Programs))
+	var visit func(p *ProgramConfig) error
+	visit = func(p *ProgramConfig) error {
+		switch states[p.Name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle through program %q", p.Name)
+		case visited:
+			return nil
+		}
+		states[p.Name] = visiting
+		for _, dep := range p.DependsOn {
+			if err := visit(byName[dep]); err != nil {
+				return err
+			}
+		}
+		states[p.Name] = visited
+		sorted = append(sorted, p)
+		return nil
+	}
+	for _, p := range c.Programs {
+		if err := visit(p); err != nil {
+			return err
+		}
+	}
+	c.Programs = sorted
+	return nil
+}
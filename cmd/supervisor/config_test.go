@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Programs: []*ProgramConfig{
+		{Name: "api", Command: []string{"api"}, DependsOn: []string{"db", "cache"}},
+		{Name: "cache", Command: []string{"cache"}, DependsOn: []string{"db"}},
+		{Name: "db", Command: []string{"db"}, Restart: RestartAlways},
+	}}
+	if err := config.validate(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range config.Programs {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, ","); got != "db,cache,api" {
+		t.Fatalf("expect programs sorted by dependencies but got %s", got)
+	}
+	api := config.Programs[2]
+	if api.Restart != RestartOnFailure || time.Duration(api.Backoff) != time.Second || time.Duration(api.Grace) != 10*time.Second {
+		t.Fatalf("expect defaults filled but got %+v", api)
+	}
+}
+
+func TestConfigValidateError(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		programs []*ProgramConfig
+		want     string
+	}{
+		{
+			name: "cycle",
+			programs: []*ProgramConfig{
+				{Name: "a", Command: []string{"a"}, DependsOn: []string{"b"}},
+				{Name: "b", Command: []string{"b"}, DependsOn: []string{"a"}},
+			},
+			want: "dependency cycle",
+		},
+		{
+			name: "unknown dependency",
+			programs: []*ProgramConfig{
+				{Name: "a", Command: []string{"a"}, DependsOn: []string{"b"}},
+			},
+			want: "unknown program",
+		},
+		{
+			name: "duplicate",
+			programs: []*ProgramConfig{
+				{Name: "a", Command: []string{"a"}},
+				{Name: "a", Command: []string{"a"}},
+			},
+			want: "duplicate program",
+		},
+		{
+			name: "invalid restart policy",
+			programs: []*ProgramConfig{
+				{Name: "a", Command: []string{"a"}, Restart: "sometimes"},
+			},
+			want: "invalid restart policy",
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := (&Config{Programs: tc.programs}).validate()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expect error %q but got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// control handles a connection of the control socket. A request is a line
+// of a command:
+//
+//	status
+//	start NAME
+//	stop NAME
+//	restart NAME
+//
+// and the response is written back before the connection is closed.
+func control(programs []*program) func(ctx context.Context, conn net.Conn) error {
+	byName := make(map[string]*program)
+	for _, p := range programs {
+		byName[p.config.Name] = p
+	}
+	return func(ctx context.Context, conn net.Conn) error {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			_, err := fmt.Fprintln(conn, "error: empty command")
+			return err
+		}
+		if fields[0] == "status" {
+			return writeStatus(conn, programs)
+		}
+
+		kinds := map[string]commandKind{
+			"start":   startCommand,
+			"stop":    stopCommand,
+			"restart": restartCommand,
+		}
+		kind, ok := kinds[fields[0]]
+		if !ok || len(fields) != 2 {
+			_, err := fmt.Fprintf(conn, "error: invalid command %q\n", strings.TrimSpace(line))
+			return err
+		}
+		p, ok := byName[fields[1]]
+		if !ok {
+			_, err := fmt.Fprintf(conn, "error: unknown program %q\n", fields[1])
+			return err
+		}
+		if err := p.send(ctx, kind); err != nil {
+			_, err := fmt.Fprintf(conn, "error: %v\n", err)
+			return err
+		}
+		_, err = fmt.Fprintln(conn, "ok")
+		return err
+	}
+}
+
+func writeStatus(w io.Writer, programs []*program) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATE\tPID\tRESTARTS\tLAST ERROR")
+	for _, p := range programs {
+		s := p.status()
+		pid, lastErr := "-", "-"
+		if s.Pid != 0 {
+			pid = fmt.Sprint(s.Pid)
+		}
+		if s.LastErr != nil {
+			lastErr = s.LastErr.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", s.Name, s.State, pid, s.Restarts, lastErr)
+	}
+	return tw.Flush()
+}
+
+// ctl sends a command to the control socket and prints the response
+func ctl(socket string, args []string) error {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, strings.Join(args, " ")); err != nil {
+		return err
+	}
+	resp, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(resp))
+	if strings.HasPrefix(string(resp), "error:") {
+		return fmt.Errorf("command %q failed", strings.Join(args, " "))
+	}
+	return nil
+}
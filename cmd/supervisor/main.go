@@ -0,0 +1,147 @@
+// Command supervisor runs and supervises a set of programs described by a
+// config file, e.g. a local development stack.
+//
+// Usage:
+//
+//	supervisor [-config supervisor.json]
+//	supervisor ctl [-socket supervisor.sock] status|start NAME|stop NAME|restart NAME
+//
+// The config file is in JSON:
+//
+//	{
+//		"socket": "supervisor.sock",
+//		"programs": [
+//			{
+//				"name": "db",
+//				"command": ["postgres", "-D", "data"],
+//				"restart": "always",
+//				"grace": "30s"
+//			},
+//			{
+//				"name": "api",
+//				"command": ["./api", "-port", "8080"],
+//				"env": {"DB": "localhost:5432"},
+//				"restart": "on-failure",
+//				"backoff": "2s",
+//				"depends_on": ["db"]
+//			}
+//		]
+//	}
+//
+// Each program is started after its dependencies, and restarted by its
+// restart policy (always, on-failure or never) after the backoff delay. The
+// output of the programs is logged line by line with their names.
+//
+// On SIGINT or SIGTERM, the programs are stopped in the reverse order of
+// their dependencies: each one is sent SIGTERM, and SIGKILL after its grace
+// period. SIGHUP, SIGUSR1 and SIGUSR2 are forwarded to the running programs.
+//
+// The status of the programs can be queried, and each program can be
+// stopped, started or restarted via the control socket by the ctl
+// subcommand.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/server"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		fs := flag.NewFlagSet("ctl", flag.ExitOnError)
+		socket := fs.String("socket", "supervisor.sock", "path of the control socket")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "usage: supervisor ctl [-socket path] status|start NAME|stop NAME|restart NAME")
+			os.Exit(2)
+		}
+		if err := ctl(*socket, fs.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configPath := flag.String("config", "supervisor.json", "path of the config file")
+	flag.Parse()
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := supervise(config); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// supervise runs the programs until SIGINT or SIGTERM is received
+func supervise(config *Config) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	programs := newPrograms(config)
+	// the programs are stopped in LIFO order, i.e. a program is stopped
+	// before its dependencies, and the control socket is closed first
+	stack := run.NewStack(run.ShutdownTimeout(shutdownTimeout(config)))
+	for _, p := range programs {
+		stack.Start(ctx, p)
+	}
+	os.Remove(config.Socket) // a stale socket left by a crash
+	listener, err := net.Listen("unix", config.Socket)
+	if err != nil {
+		stack.Close()
+		return err
+	}
+	stack.Start(ctx, server.NewListener(listener, control(programs)))
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, append([]os.Signal{os.Interrupt, syscall.SIGTERM}, forwardedSignals...)...)
+	defer signal.Stop(sigChan)
+	for sig := range sigChan {
+		if sig == os.Interrupt || sig == syscall.SIGTERM {
+			log.Printf("supervisor: %v received, stopping", sig)
+			break
+		}
+		for _, p := range programs {
+			if err := p.cmd.Signal(sig); err == nil {
+				log.Printf("supervisor: %v forwarded to %s", sig, p.config.Name)
+			}
+		}
+	}
+	return stack.Close()
+}
+
+// newPrograms creates the programs in the order of config.Programs, which is
+// sorted by dependencies
+func newPrograms(config *Config) []*program {
+	byName := make(map[string]*program)
+	programs := make([]*program, len(config.Programs))
+	for i, c := range config.Programs {
+		p := newProgram(c)
+		for _, dep := range c.DependsOn {
+			p.deps = append(p.deps, byName[dep])
+		}
+		byName[c.Name] = p
+		programs[i] = p
+	}
+	return programs
+}
+
+// shutdownTimeout allows each program to use its whole grace period, as they
+// are stopped one by one
+func shutdownTimeout(config *Config) time.Duration {
+	timeout := 10 * time.Second
+	for _, p := range config.Programs {
+		timeout += time.Duration(p.Grace)
+	}
+	return timeout
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/proc"
+)
+
+// State of a supervised program
+type State string
+
+// State constants
+const (
+	Waiting State = "waiting" // waiting for its dependencies
+	Running State = "running" // the process is running
+	Backoff State = "backoff" // waiting to restart after an exit
+	Exited  State = "exited"  // exited and not restarted by its policy
+	Stopped State = "stopped" // stopped by a command
+)
+
+// errBusy is returned when a program cannot accept a command in time
+var errBusy = errors.New("program busy, try again later")
+
+type commandKind int
+
+const (
+	startCommand commandKind = iota
+	stopCommand
+	restartCommand
+)
+
+// program is a runner supervising a subprocess by its restart policy, and
+// accepting commands from the control socket
+type program struct {
+	config   *ProgramConfig
+	cmd      *proc.Cmd
+	deps     []*program
+	commands chan commandKind
+
+	readyOnce sync.Once
+	ready     chan struct{}
+
+	mu       sync.Mutex
+	state    State
+	restarts int
+	lastErr  error
+}
+
+func newProgram(config *ProgramConfig) *program {
+	cmd := proc.Command(config.Command[0], config.Command[1:]...)
+	cmd.Dir = config.Dir
+	if len(config.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range config.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	cmd.Grace = time.Duration(config.Grace)
+	cmd.Output = func(stream proc.Stream, line string) {
+		log.Printf("%s | %s", config.Name, line)
+	}
+	return &program{
+		config:   config,
+		cmd:      cmd,
+		commands: make(chan commandKind),
+		ready:    make(chan struct{}),
+		state:    Waiting,
+	}
+}
+
+// Name returns the name of the program
+func (p *program) Name() string {
+	return p.config.Name
+}
+
+// Ready returns a channel that is closed once the program is started for the
+// first time
+func (p *program) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// send sends a command to the program, it returns errBusy if the program
+// does not accept it in time, e.g. when it is waiting for its dependencies
+func (p *program) send(ctx context.Context, kind commandKind) error {
+	timer := run.ClockFromContext(ctx).NewTimer(time.Second)
+	defer timer.Stop()
+	select {
+	case p.commands <- kind:
+		return nil
+	case <-timer.C():
+		return errBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run waits for the dependencies to be ready, and then runs the process
+// repeatedly by its restart policy and the commands until ctx is cancelled.
+// It always returns nil, as a failing program should not stop the others.
+func (p *program) Run(ctx context.Context) error {
+	for _, dep := range p.deps {
+		select {
+		case <-dep.Ready():
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	stopped := false
+	for {
+		if stopped {
+			kind, ok := p.idle(ctx)
+			if !ok {
+				return nil
+			}
+			if kind == restartCommand {
+				p.restart()
+			}
+			stopped = false
+		}
+
+		p.setState(Running)
+		p.readyOnce.Do(func() { close(p.ready) })
+		kind, err := p.runOnce(ctx)
+		p.exit(err)
+		if ctx.Err() != nil {
+			return nil
+		}
+		switch kind {
+		case stopCommand:
+			p.setState(Stopped)
+			stopped = true
+			continue
+		case restartCommand:
+			p.restart()
+			continue
+		}
+
+		if !p.shouldRestart(err) {
+			p.setState(Exited)
+			stopped = true
+			continue
+		}
+		p.setState(Backoff)
+		if kind, ok := p.backoff(ctx); !ok {
+			return nil
+		} else if kind == stopCommand {
+			p.setState(Stopped)
+			stopped = true
+			continue
+		}
+		p.restart()
+	}
+}
+
+// runOnce runs the process until it exits, or ctx is cancelled, or a stop or
+// restart command terminates it
+func (p *program) runOnce(ctx context.Context) (commandKind, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- p.cmd.Run(runCtx)
+	}()
+	for {
+		select {
+		case err := <-errChan:
+			return startCommand, err
+		case kind := <-p.commands:
+			if kind == startCommand {
+				continue // already running
+			}
+			log.Printf("%s: %s", p.config.Name, kindName(kind))
+			cancel()
+			return kind, <-errChan
+		case <-ctx.Done():
+			return startCommand, <-errChan
+		}
+	}
+}
+
+// idle waits for a start or restart command and returns it, it returns
+// false if ctx is cancelled
+func (p *program) idle(ctx context.Context) (commandKind, bool) {
+	for {
+		select {
+		case kind := <-p.commands:
+			if kind != stopCommand {
+				log.Printf("%s: %s", p.config.Name, kindName(kind))
+				return kind, true
+			}
+		case <-ctx.Done():
+			return startCommand, false
+		}
+	}
+}
+
+// backoff waits for the backoff delay or a command, it returns false if ctx
+// is cancelled
+func (p *program) backoff(ctx context.Context) (commandKind, bool) {
+	timer := run.ClockFromContext(ctx).NewTimer(time.Duration(p.config.Backoff))
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return startCommand, true
+	case kind := <-p.commands:
+		log.Printf("%s: %s", p.config.Name, kindName(kind))
+		return kind, true
+	case <-ctx.Done():
+		return startCommand, false
+	}
+}
+
+func (p *program) shouldRestart(err error) bool {
+	switch p.config.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	}
+	return false
+}
+
+func (p *program) setState(state State) {
+	p.mu.Lock()
+	p.state = state
+	p.mu.Unlock()
+	log.Printf("%s: %s", p.config.Name, state)
+}
+
+func (p *program) exit(err error) {
+	p.mu.Lock()
+	p.lastErr = err
+	p.mu.Unlock()
+	if err != nil {
+		log.Printf("%s: %v", p.config.Name, err)
+	}
+}
+
+func (p *program) restart() {
+	p.mu.Lock()
+	p.restarts++
+	p.mu.Unlock()
+}
+
+// Status is a snapshot of the status of a program
+type Status struct {
+	Name     string
+	State    State
+	Pid      int
+	Restarts int
+	LastErr  error
+}
+
+func (p *program) status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Status{
+		Name:     p.config.Name,
+		State:    p.state,
+		Pid:      p.cmd.Pid(),
+		Restarts: p.restarts,
+		LastErr:  p.lastErr,
+	}
+}
+
+func kindName(kind commandKind) string {
+	switch kind {
+	case startCommand:
+		return "start"
+	case stopCommand:
+		return "stop"
+	case restartCommand:
+		return "restart"
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestProgramRestart(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Programs: []*ProgramConfig{
+		{Name: "flaky", Command: []string{"sh", "-c", "exit 1"}, Backoff: Duration(time.Millisecond)},
+		{Name: "once", Command: []string{"true"}, Restart: RestartNever, DependsOn: []string{"flaky"}},
+	}}
+	if err := config.validate(); err != nil {
+		t.Fatal(err)
+	}
+	programs := newPrograms(config)
+	stack := run.NewStack()
+	for _, p := range programs {
+		stack.Start(context.Background(), p)
+	}
+	for programs[0].status().Restarts < 3 || programs[1].status().State != Exited {
+		time.Sleep(time.Millisecond)
+	}
+	if err := stack.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProgramControl(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{Programs: []*ProgramConfig{
+		{Name: "sleep", Command: []string{"sleep", "10"}, Grace: Duration(time.Second)},
+	}}
+	if err := config.validate(); err != nil {
+		t.Fatal(err)
+	}
+	programs := newPrograms(config)
+	h := run.Start(context.Background(), programs[0])
+	defer h.Stop(context.Background())
+	<-h.Ready()
+	handler := control(programs)
+	request := func(line string) string {
+		client, server := net.Pipe()
+		defer client.Close()
+		go func() {
+			defer server.Close()
+			handler(context.Background(), server)
+		}()
+		client.Write([]byte(line + "\n"))
+		var resp bytes.Buffer
+		resp.ReadFrom(client)
+		return resp.String()
+	}
+
+	if resp := request("stop sleep"); resp != "ok\n" {
+		t.Fatalf("expect ok but got %q", resp)
+	}
+	for programs[0].status().State != Stopped {
+		time.Sleep(time.Millisecond)
+	}
+	if resp := request("status"); !strings.Contains(resp, "sleep  stopped") {
+		t.Fatalf("expect status stopped but got %q", resp)
+	}
+	if resp := request("start sleep"); resp != "ok\n" {
+		t.Fatalf("expect ok but got %q", resp)
+	}
+	for programs[0].status().State != Running {
+		time.Sleep(time.Millisecond)
+	}
+	if n := programs[0].status().Restarts; n != 0 {
+		t.Fatalf("expect no restarts on start but got %d", n)
+	}
+
+	// a restart from the stopped state counts as a restart
+	if resp := request("stop sleep"); resp != "ok\n" {
+		t.Fatalf("expect ok but got %q", resp)
+	}
+	for programs[0].status().State != Stopped {
+		time.Sleep(time.Millisecond)
+	}
+	if resp := request("restart sleep"); resp != "ok\n" {
+		t.Fatalf("expect ok but got %q", resp)
+	}
+	for programs[0].status().State != Running {
+		time.Sleep(time.Millisecond)
+	}
+	if n := programs[0].status().Restarts; n != 1 {
+		t.Fatalf("expect 1 restart but got %d", n)
+	}
+	if resp := request("restart nope"); !strings.HasPrefix(resp, "error: unknown program") {
+		t.Fatalf("expect error but got %q", resp)
+	}
+}
+
+func TestProgramSendBusy(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	p := newProgram(&ProgramConfig{Name: "waiting", Command: []string{"true"}})
+	errChan := make(chan error)
+	go func() { errChan <- p.send(ctx, startCommand) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-errChan; err != errBusy {
+		t.Fatalf("expect %v but got %v", errBusy, err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// forwardedSignals are forwarded to the running programs, Windows does not
+// support any
+var forwardedSignals []os.Signal
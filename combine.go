@@ -0,0 +1,183 @@
+package run
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Serial returns a runner that runs runners one after another, and stops at
+// the first error and returns it
+func Serial(runners ...Runner) Runner {
+	return &composite{
+		kind:    "serial",
+		runners: runners,
+		run: func(ctx context.Context, runners []Runner) error {
+			for _, runner := range runners {
+				if err := runner.Run(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// All returns a runner that runs runners concurrently with the semantics of a
+// group: the first error cancels the others and is returned after all of them
+// exit
+func All(runners ...Runner) Runner {
+	return &composite{
+		kind:    "all",
+		runners: runners,
+		run: func(ctx context.Context, runners []Runner) (err error) {
+			fanOut(ctx, runners, func(i int, e error) bool {
+				err = e
+				return e != nil
+			})
+			return err
+		},
+	}
+}
+
+// Race returns a runner that runs runners concurrently, and returns the result
+// of the first one to exit, either success or failure, after the others are
+// cancelled and exit. It panics if runners is empty.
+func Race(runners ...Runner) Runner {
+	if len(runners) == 0 {
+		panic("no runners to race")
+	}
+	return &composite{
+		kind:    "race",
+		runners: runners,
+		run: func(ctx context.Context, runners []Runner) (err error) {
+			fanOut(ctx, runners, func(i int, e error) bool {
+				err = e
+				return true
+			})
+			return err
+		},
+	}
+}
+
+// Any returns a runner that runs runners concurrently, and returns nil once
+// one of them succeeds, after the others are cancelled and exit. If all of
+// them fail, Errors is returned. It panics if runners is empty.
+func Any(runners ...Runner) Runner {
+	if len(runners) == 0 {
+		panic("no runners to run")
+	}
+	return &composite{
+		kind:    "any",
+		runners: runners,
+		run:     quorum(1),
+	}
+}
+
+// Quorum returns a runner that runs runners concurrently, and returns nil once
+// k of them succeed, after the others are cancelled and exit. Once the quorum
+// can no longer be reached, the others are cancelled as well and the errors of
+// the failed ones are returned as Errors. It panics if k is not within
+// [1, len(runners)].
+func Quorum(k int, runners ...Runner) Runner {
+	if k < 1 || k > len(runners) {
+		panic("quorum should be within [1, len(runners)]")
+	}
+	return &composite{
+		kind:    "quorum",
+		arg:     strconv.Itoa(k),
+		runners: runners,
+		run:     quorum(k),
+	}
+}
+
+func quorum(k int) func(ctx context.Context, runners []Runner) error {
+	return func(ctx context.Context, runners []Runner) error {
+		errs := make([]error, len(runners))
+		succeeded, failed := 0, 0
+		fanOut(ctx, runners, func(i int, err error) bool {
+			if err == nil {
+				succeeded++
+				return succeeded == k
+			}
+			errs[i] = err
+			failed++
+			return failed > len(runners)-k
+		})
+		if succeeded >= k {
+			return nil
+		}
+		var result Errors
+		for _, err := range errs {
+			if err != nil {
+				result = append(result, err)
+			}
+		}
+		return result
+	}
+}
+
+// composite is a runner composed of multiple runners
+type composite struct {
+	kind    string
+	arg     string // optional argument before the runners
+	runners []Runner
+	run     func(ctx context.Context, runners []Runner) error
+}
+
+// Name returns the kind of the composite runner followed by the names of its
+// runners, e.g. "any(a, b)" or "quorum(2, a, b, c)"
+func (c *composite) Name() string {
+	var b strings.Builder
+	b.WriteString(c.kind)
+	b.WriteByte('(')
+	if c.arg != "" {
+		b.WriteString(c.arg)
+		b.WriteString(", ")
+	}
+	for i, runner := range c.runners {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(Name(runner))
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func (c *composite) Run(ctx context.Context) error {
+	return c.run(ctx, c.runners)
+}
+
+// fanOut runs runners concurrently on the pool from PoolFromContext, and
+// calls decide with the result of each runner in the order of their exits,
+// until decide returns true, then the remaining runners are cancelled. It
+// returns after all the runners exit.
+//
+// If a runner fails to be dispatched onto the pool, the error is passed to
+// decide as the result of the runner.
+func fanOut(ctx context.Context, runners []Runner, decide func(i int, err error) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		i   int
+		err error
+	}
+	results := make(chan result, len(runners))
+	for i, runner := range runners {
+		i, runner := i, runner
+		if err := goFunc(ctx, func() {
+			results <- result{i: i, err: runner.Run(ctx)}
+		}); err != nil {
+			results <- result{i: i, err: err}
+		}
+	}
+	decided := false
+	for range runners {
+		r := <-results
+		if !decided && decide(r.i, r.err) {
+			decided = true
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestSerial(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	runners := []*runtest.FakeRunner{{}, {Err: errRun}, {}}
+	err := run.Serial(runners[0], runners[1], runners[2]).Run(context.Background())
+	if err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	for i, wantCalls := range []int{1, 1, 0} {
+		if calls := runners[i].Calls(); calls != wantCalls {
+			t.Fatalf("expect runner %d called %d times but got %d", i, wantCalls, calls)
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	defer runtest.Check(t)()
+
+	errRun := errors.New("err run")
+	blocked := &runtest.FakeRunner{Block: true}
+	err := run.All(&runtest.FakeRunner{}, blocked, &runtest.FakeRunner{Err: errRun}).Run(context.Background())
+	if err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	if err := run.All(&runtest.FakeRunner{}, &runtest.FakeRunner{}).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRace(t *testing.T) {
+	defer runtest.Check(t)()
+
+	errRun := errors.New("err run")
+	err := run.Race(&runtest.FakeRunner{Block: true}, &runtest.FakeRunner{Err: errRun}).Run(context.Background())
+	if err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	if err := run.Race(&runtest.FakeRunner{Block: true}, &runtest.FakeRunner{}).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	errRun := errors.New("err run")
+	ok := func() run.Runner { return &runtest.FakeRunner{} }
+	fail := func() run.Runner { return &runtest.FakeRunner{Err: errRun} }
+	block := func() run.Runner { return &runtest.FakeRunner{Block: true} }
+	testcases := []struct {
+		name       string
+		runner     run.Runner
+		wantErrors int
+	}{
+		{"any succeeds", run.Any(fail(), block(), ok()), 0},
+		{"any fails", run.Any(fail(), fail()), 2},
+		{"quorum reached", run.Quorum(2, ok(), fail(), block(), ok()), 0},
+		{"quorum unreachable", run.Quorum(2, fail(), block(), fail()), 2},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer runtest.Check(t)()
+			err := tc.runner.Run(context.Background())
+			if tc.wantErrors == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var errs run.Errors
+			if !errors.As(err, &errs) || len(errs) != tc.wantErrors {
+				t.Fatalf("expect %d errors but got %v", tc.wantErrors, err)
+			}
+			if !errors.Is(err, errRun) {
+				t.Fatalf("expect error %v within %v", errRun, err)
+			}
+		})
+	}
+}
+
+func TestCombinatorNesting(t *testing.T) {
+	defer runtest.Check(t)()
+
+	var pool countingPool
+	ctx := run.WithPool(context.Background(), &pool)
+	runner := run.Serial(
+		run.All(&runtest.FakeRunner{}, &runtest.FakeRunner{}),
+		run.Any(&runtest.FakeRunner{Block: true}, &runtest.FakeRunner{}),
+	)
+	if err := runner.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&pool.n); n != 4 {
+		t.Fatalf("expect 4 runners dispatched onto the pool but got %d", n)
+	}
+}
+
+func TestCombinatorName(t *testing.T) {
+	t.Parallel()
+
+	a := namedRunner("a")
+	b := namedRunner("b")
+	if name := run.Name(run.Serial(a, run.Quorum(1, a, b))); name != "serial(a, quorum(1, a, b))" {
+		t.Fatalf("unexpected name %q", name)
+	}
+}
+
+type countingPool struct {
+	n int64
+}
+
+func (p *countingPool) Go(ctx context.Context, fn func()) error {
+	atomic.AddInt64(&p.n, 1)
+	go fn()
+	return nil
+}
+
+type namedRunner string
+
+func (r namedRunner) Name() string                  { return string(r) }
+func (r namedRunner) Run(ctx context.Context) error { return nil }
@@ -0,0 +1,52 @@
+package run
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Errors is a list of errors returned by multiple runners, in the order of
+// the runners
+type Errors []error
+
+// Error satisfies error interface
+func (e Errors) Error() string {
+	switch len(e) {
+	case 0:
+		return "no error"
+	case 1:
+		return e[0].Error()
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(e)))
+	b.WriteString(" errors: ")
+	for i, err := range e {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Is reports whether any of the errors matches target
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target, and if so, sets target to
+// that error value and returns true
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package run
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// EveryOption is used to specify an option for Every
+type EveryOption func(*every)
+
+// Jitter specifies the maximum random delay added to each run, so that the
+// runs of many periodic runners are spread out
+func Jitter(jitter time.Duration) EveryOption {
+	return func(e *every) {
+		e.jitter = jitter
+	}
+}
+
+// Immediate specifies that the first run starts immediately instead of
+// waiting for the first tick
+func Immediate() EveryOption {
+	return func(e *every) {
+		e.immediate = true
+	}
+}
+
+// QueueOne specifies that when ticks arrive while the runner is still
+// running, one run is queued and starts right after the current run. If not
+// specified, such ticks are skipped.
+func QueueOne() EveryOption {
+	return func(e *every) {
+		e.queueOne = true
+	}
+}
+
+// Align specifies that the ticks are aligned to the multiples of the interval
+// since the zero time, e.g. an hourly runner ticks at the beginning of each
+// hour
+func Align() EveryOption {
+	return func(e *every) {
+		e.align = true
+	}
+}
+
+// OnMissed specifies a function to be called with the number of ticks
+// skipped because the runner was still running
+func OnMissed(f func(missed int)) EveryOption {
+	return func(e *every) {
+		e.onMissed = f
+	}
+}
+
+// Every returns a runner that runs runner periodically at the interval,
+// measured by the clock from ClockFromContext.
+//
+// It returns the first error returned by runner, or nil when the context is
+// cancelled.
+func Every(interval time.Duration, runner Runner, options ...EveryOption) Runner {
+	if interval <= 0 {
+		panic("interval should always be positive")
+	}
+	e := &every{
+		interval: interval,
+		runner:   runner,
+	}
+	for _, opt := range options {
+		opt(e)
+	}
+	return e
+}
+
+type every struct {
+	interval  time.Duration
+	runner    Runner
+	jitter    time.Duration
+	immediate bool
+	queueOne  bool
+	align     bool
+	onMissed  func(missed int)
+}
+
+// Name returns the name of the underlying runner
+func (e *every) Name() string {
+	return Name(e.runner)
+}
+
+func (e *every) Run(ctx context.Context) error {
+	clock := ClockFromContext(ctx)
+	now := clock.Now()
+	tick := now.Add(e.interval)
+	if e.align {
+		tick = now.Truncate(e.interval).Add(e.interval)
+	}
+	runNow := e.immediate
+	for {
+		if !runNow {
+			delay := tick.Sub(clock.Now())
+			if e.jitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(e.jitter)))
+			}
+			if !sleep(ctx, clock, delay) {
+				return nil
+			}
+			tick = tick.Add(e.interval)
+		}
+		runNow = false
+
+		if err := e.runner.Run(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		// count the ticks passed while running
+		now := clock.Now()
+		if tick.After(now) {
+			continue
+		}
+		passed := int(now.Sub(tick)/e.interval) + 1
+		tick = tick.Add(time.Duration(passed) * e.interval)
+		if e.queueOne {
+			runNow = true
+			passed--
+		}
+		if passed > 0 && e.onMissed != nil {
+			e.onMissed(passed)
+		}
+	}
+}
+
+// sleep waits for d and returns true, or returns false if ctx is cancelled
+func sleep(ctx context.Context, clock Clock, d time.Duration) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if d <= 0 {
+		return true
+	}
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,135 @@
+package run_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		options   []run.EveryOption
+		wantCalls int
+	}{
+		{
+			name:      "default",
+			wantCalls: 3,
+		},
+		{
+			name:      "immediate",
+			options:   []run.EveryOption{run.Immediate()},
+			wantCalls: 4,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			clock := runtest.NewFakeClock(time.Now())
+			ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+			runner := &runtest.FakeRunner{}
+			errChan := make(chan error)
+			go func() {
+				errChan <- run.Every(time.Second, runner, tc.options...).Run(ctx)
+			}()
+			for i := 0; i < 3; i++ {
+				clock.BlockUntil(1)
+				clock.Advance(time.Second)
+			}
+			clock.BlockUntil(1)
+			cancel()
+			if err := <-errChan; err != nil {
+				t.Fatal(err)
+			}
+			if calls := runner.Calls(); calls != tc.wantCalls {
+				t.Fatalf("expect %d calls but got %d", tc.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestEveryAlign(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 4, 16, 10, 30, 15, 0, time.UTC)
+	clock := runtest.NewFakeClock(start)
+	ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+	defer cancel()
+	ranAt := make(chan time.Time, 1)
+	go run.Every(time.Minute, run.Func(func(ctx context.Context) error {
+		ranAt <- clock.Now()
+		return nil
+	}), run.Align()).Run(ctx)
+	clock.BlockUntil(1)
+	clock.Advance(45 * time.Second)
+	want := time.Date(2021, 4, 16, 10, 31, 0, 0, time.UTC)
+	if got := <-ranAt; !got.Equal(want) {
+		t.Fatalf("expect run at %v got %v", want, got)
+	}
+}
+
+func TestEveryMissed(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		options    []run.EveryOption
+		wantMissed int
+		wantCalls  int
+	}{
+		{
+			name:       "skip",
+			wantMissed: 3,
+			wantCalls:  1,
+		},
+		{
+			name:       "queue one",
+			options:    []run.EveryOption{run.QueueOne()},
+			wantMissed: 2,
+			wantCalls:  2,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			clock := runtest.NewFakeClock(time.Now())
+			ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+			missedChan := make(chan int, 1)
+			calls := 0
+			options := append(tc.options, run.OnMissed(func(missed int) {
+				missedChan <- missed
+			}))
+			errChan := make(chan error)
+			go func() {
+				errChan <- run.Every(time.Second, run.Func(func(ctx context.Context) error {
+					calls++
+					if calls == 1 {
+						clock.Advance(3 * time.Second) // overrun 3 ticks
+					}
+					return nil
+				}), options...).Run(ctx)
+			}()
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			missed := <-missedChan
+			clock.BlockUntil(1)
+			cancel()
+			if err := <-errChan; err != nil {
+				t.Fatal(err)
+			}
+			if missed != tc.wantMissed {
+				t.Fatalf("expect %d missed but got %d", tc.wantMissed, missed)
+			}
+			if calls != tc.wantCalls {
+				t.Fatalf("expect %d calls but got %d", tc.wantCalls, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"h12.io/run"
+	"h12.io/run/gopool"
+)
+
+// WorkerPool stands for a goroutine pool from a third-party package, with a
+// fixed number of workers and its own API for submitting tasks
+type WorkerPool struct {
+	tasks chan func()
+	wg    sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+var ErrStopped = errors.New("worker pool stopped")
+
+func NewWorkerPool(workers, queue int) *WorkerPool {
+	p := &WorkerPool{tasks: make(chan func(), queue)}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// Submit queues task, it blocks if the queue is full
+func (p *WorkerPool) Submit(task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrStopped
+	}
+	p.tasks <- task
+	return nil
+}
+
+// Stop waits for the queued tasks to complete
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	close(p.tasks)
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// poolAdapter adapts WorkerPool into a gopool.GroupPool. As Submit cannot be
+// cancelled, the context is only checked before submitting.
+type poolAdapter struct {
+	pool *WorkerPool
+}
+
+func (a poolAdapter) Go(ctx context.Context, fn func()) error {
+	if ctx.Err() != nil {
+		return gopool.ErrDispatchTimeout
+	}
+	return a.pool.Submit(fn)
+}
+
+func main() {
+	pool := NewWorkerPool(4, 16)
+	defer pool.Stop()
+
+	group := gopool.NewGroup(context.Background(), gopool.Pool(poolAdapter{pool}))
+	results := make([]int, 10)
+	for i := range results {
+		i := i
+		if err := group.Go(run.Func(func(ctx context.Context) error {
+			results[i] = i * i
+			return nil
+		})); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := group.Wait(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(results)
+}
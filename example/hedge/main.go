@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/gopool"
+)
+
+// GoogleSearch is the "Google Search 3.0" example from
+// https://talks.golang.org/2012/concurrency.slide#50
+type GoogleSearch struct {
+	Query  string
+	Search Search
+	Result Result
+}
+
+type Result string
+
+var (
+	Web1   = fakeSearch("web1")
+	Web2   = fakeSearch("web2")
+	Image1 = fakeSearch("image1")
+	Image2 = fakeSearch("image2")
+	Video1 = fakeSearch("video1")
+	Video2 = fakeSearch("video2")
+)
+
+type Search func(ctx context.Context, query string) (Result, error)
+
+func fakeSearch(kind string) Search {
+	return func(ctx context.Context, query string) (Result, error) {
+		select {
+		case <-time.After(time.Duration(rand.Intn(100)) * time.Millisecond):
+			return Result(fmt.Sprintf("%s result for %q", kind, query)), nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func (s *GoogleSearch) Run(ctx context.Context) error {
+	result, err := s.Search(ctx, s.Query)
+	if err != nil {
+		return err
+	}
+	s.Result = result
+	return nil
+}
+
+// HedgedSearch is a search hedged over the replicas
+type HedgedSearch struct {
+	*run.Hedged
+	Result Result
+}
+
+func (s *HedgedSearch) Run(ctx context.Context) error {
+	winner, err := s.Do(ctx)
+	if err != nil {
+		return err
+	}
+	s.Result = winner.(*GoogleSearch).Result
+	return nil
+}
+
+// replicated returns a hedged search over the replicas, a replica is queried
+// if the previous ones have not replied after the 90th percentile of recent
+// latencies
+func replicated(query string, replicas ...Search) *HedgedSearch {
+	return &HedgedSearch{Hedged: run.Hedge(func(attempt int) run.Runner {
+		return &GoogleSearch{Query: query, Search: replicas[attempt%len(replicas)]}
+	}, run.PercentileDelay(0.9, 100, 20*time.Millisecond), len(replicas)-1)}
+}
+
+func main() {
+	pool := gopool.NewGoroutinePool(
+		gopool.Max(16),
+		gopool.IdleTime(time.Minute),
+	)
+	defer pool.Close()
+
+	searches := []*HedgedSearch{
+		replicated("golang", Web1, Web2),
+		replicated("golang", Image1, Image2),
+		replicated("golang", Video1, Video2),
+	}
+	all := run.WithTimeout(run.All(searches[0], searches[1], searches[2]), 80*time.Millisecond)
+
+	// the searches and their replicas are dispatched onto the pool
+	ctx := run.WithPool(context.Background(), pool)
+	if err := all.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, search := range searches {
+		fmt.Println(search.Result)
+	}
+}
@@ -0,0 +1,283 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"h12.io/run"
+)
+
+// ErrCircuitOpen is returned when a runner is rejected by an open
+// CircuitBreaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState enum of a CircuitBreaker
+type BreakerState int
+
+// BreakerState constants
+const (
+	BreakerClosed   BreakerState = iota // runs are allowed
+	BreakerOpen                         // runs fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // a single probing run is allowed
+)
+
+// String representation of int enum
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return ""
+}
+
+// CircuitBreaker is a runner wrapping another runner, which tracks the failure
+// ratio of the runner over a rolling window.
+//
+// When the failure ratio reaches the threshold, the breaker opens and fails
+// fast with ErrCircuitOpen. After the cooldown, the breaker becomes half-open
+// and allows a single probing run, which closes the breaker if it succeeds,
+// or opens the breaker again otherwise. Runs failed due to the cancellation
+// of their contexts are not counted.
+//
+// The breaker is a run.Admitter, so a Group does not dispatch an open breaker
+// onto its pool, even if it is wrapped by a runner forwarding Admit, e.g.
+// run.WithTimeout. Its state changes are logged by the group running it.
+type CircuitBreaker struct {
+	runner      Runner
+	window      time.Duration
+	ratio       float64
+	minRequests int
+	cooldown    time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	openedAt time.Time
+	probing  bool
+	buckets  [breakerBuckets]breakerBucket
+}
+
+// the rolling window is divided into buckets
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// BreakerOption is used to specify an option for CircuitBreaker
+type BreakerOption func(*CircuitBreaker)
+
+// BreakerWindow specifies the duration of the rolling window over which the
+// failure ratio is calculated, default to 10s. The window is divided into 10
+// buckets, so it should be at least 10ns.
+func BreakerWindow(window time.Duration) BreakerOption {
+	if window < breakerBuckets {
+		panic("breaker window should be at least 10ns")
+	}
+	return func(b *CircuitBreaker) {
+		b.window = window
+	}
+}
+
+// BreakerThreshold specifies the failure ratio that opens the breaker, which
+// is only effective when there are at least minRequests runs within the
+// window, default to 0.5 and 10
+func BreakerThreshold(ratio float64, minRequests int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.ratio = ratio
+		b.minRequests = minRequests
+	}
+}
+
+// BreakerCooldown specifies the duration that the breaker stays open before
+// becoming half-open, default to 5s
+func BreakerCooldown(cooldown time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.cooldown = cooldown
+	}
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker wrapping runner. The
+// durations are measured by the clock from run.ClockFromContext of the
+// context passed to Run.
+func NewCircuitBreaker(runner Runner, options ...BreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{
+		runner:      runner,
+		window:      10 * time.Second,
+		ratio:       0.5,
+		minRequests: 10,
+		cooldown:    5 * time.Second,
+	}
+	for _, opt := range options {
+		opt(b)
+	}
+	return b
+}
+
+// Name returns the name of the underlying runner
+func (b *CircuitBreaker) Name() string {
+	return logName(b.runner)
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Run runs the underlying runner if the breaker allows, otherwise it returns
+// ErrCircuitOpen immediately
+func (b *CircuitBreaker) Run(ctx context.Context) (err error) {
+	probe, err := b.allow(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			b.record(ctx, probe, errors.New("panic"))
+			panic(r)
+		}
+	}()
+	err = b.runner.Run(ctx)
+	b.record(ctx, probe, err)
+	return err
+}
+
+// Admit returns ErrCircuitOpen if the breaker is going to reject a run, so
+// that the runner is not dispatched at all
+func (b *CircuitBreaker) Admit(ctx context.Context) error {
+	now := run.ClockFromContext(ctx).Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+	}
+	return nil
+}
+
+// allow returns nil if a run is allowed, and if it is a probing run
+func (b *CircuitBreaker) allow(ctx context.Context) (probe bool, err error) {
+	now := run.ClockFromContext(ctx).Now()
+	b.mu.Lock()
+	from := b.state
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+	switch b.state {
+	case BreakerOpen:
+		err = ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			err = ErrCircuitOpen
+		} else {
+			b.probing = true
+			probe = true
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.logState(ctx, from, to)
+	return probe, err
+}
+
+// record records the result of a run
+func (b *CircuitBreaker) record(ctx context.Context, probe bool, err error) {
+	now := run.ClockFromContext(ctx).Now()
+	b.mu.Lock()
+	from := b.state
+	b.update(now, probe, err != nil && ctx.Err() == nil, err == nil)
+	to := b.state
+	b.mu.Unlock()
+
+	b.logState(ctx, from, to)
+}
+
+// update updates the state with the result of a run, a run failed due to
+// cancellation is neither failed nor succeeded
+func (b *CircuitBreaker) update(now time.Time, probe, failed, succeeded bool) {
+	if probe {
+		b.probing = false
+		switch {
+		case failed:
+			b.state = BreakerOpen
+			b.openedAt = now
+		case succeeded:
+			b.state = BreakerClosed
+			b.buckets = [breakerBuckets]breakerBucket{}
+		}
+		return
+	}
+	if b.state != BreakerClosed {
+		return
+	}
+	bucket := b.bucket(now)
+	switch {
+	case failed:
+		bucket.failures++
+		if b.tripped(now) {
+			b.state = BreakerOpen
+			b.openedAt = now
+		}
+	case succeeded:
+		bucket.successes++
+	}
+}
+
+// bucket returns the bucket of now, which is reset if it is outdated
+func (b *CircuitBreaker) bucket(now time.Time) *breakerBucket {
+	size := b.window / breakerBuckets
+	start := now.Truncate(size)
+	bucket := &b.buckets[start.UnixNano()/int64(size)%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// tripped returns if the failure ratio within the window reaches the threshold
+func (b *CircuitBreaker) tripped(now time.Time) bool {
+	successes, failures := 0, 0
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.window {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+	total := successes + failures
+	return total >= b.minRequests && float64(failures) >= b.ratio*float64(total)
+}
+
+// logState logs the state change to the log function of the group
+func (b *CircuitBreaker) logState(ctx context.Context, from, to BreakerState) {
+	logFunc := logFuncFromContext(ctx)
+	if from == to || logFunc == nil {
+		return
+	}
+	event := CircuitClose
+	switch to {
+	case BreakerOpen:
+		event = CircuitOpen
+	case BreakerHalfOpen:
+		event = CircuitHalfOpen
+	}
+	logFunc(&LogInfo{
+		Runner: b,
+		Event:  event,
+	})
+}
@@ -0,0 +1,173 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestCircuitBreakerOpen(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	runner := &runtest.FakeRunner{Err: errRun}
+	breaker := NewCircuitBreaker(runner, BreakerThreshold(0.5, 2))
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := breaker.Run(ctx); err != errRun {
+			t.Fatalf("expect error %v got %v", errRun, err)
+		}
+	}
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("expect state %v got %v", BreakerOpen, state)
+	}
+	if err := breaker.Run(ctx); err != ErrCircuitOpen {
+		t.Fatalf("expect error %v got %v", ErrCircuitOpen, err)
+	}
+	if calls := runner.Calls(); calls != 2 {
+		t.Fatalf("expect the runner is not called when open, but got %d calls", calls)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	runner := &runtest.FakeRunner{Err: errRun}
+	cooldown := time.Second
+	breaker := NewCircuitBreaker(runner, BreakerThreshold(0.5, 1), BreakerCooldown(cooldown))
+
+	breaker.Run(ctx)
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("expect state %v got %v", BreakerOpen, state)
+	}
+
+	// probe fails
+	clock.Advance(cooldown)
+	if err := breaker.Run(ctx); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	if state := breaker.State(); state != BreakerOpen {
+		t.Fatalf("expect state %v got %v", BreakerOpen, state)
+	}
+	if err := breaker.Run(ctx); err != ErrCircuitOpen {
+		t.Fatalf("expect error %v got %v", ErrCircuitOpen, err)
+	}
+
+	// probe succeeds
+	clock.Advance(cooldown)
+	runner.Err = nil
+	if err := breaker.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(); state != BreakerClosed {
+		t.Fatalf("expect state %v got %v", BreakerClosed, state)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	probe := &runtest.FakeRunner{Block: true}
+	var runner Runner = &runtest.FakeRunner{Err: errors.New("err run")}
+	breaker := NewCircuitBreaker(Func(func(ctx context.Context) error {
+		return runner.Run(ctx)
+	}), BreakerThreshold(0.5, 1), BreakerCooldown(time.Second))
+	breaker.Run(ctx)
+	clock.Advance(time.Second)
+
+	runner = probe
+	errChan := make(chan error)
+	go func() {
+		errChan <- breaker.Run(ctx)
+	}()
+	<-probe.Started()
+	if err := breaker.Run(ctx); err != ErrCircuitOpen {
+		t.Fatalf("expect error %v while probing but got %v", ErrCircuitOpen, err)
+	}
+	probe.Release()
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCircuitBreakerIgnoreCancel(t *testing.T) {
+	t.Parallel()
+
+	breaker := NewCircuitBreaker(Func(func(ctx context.Context) error {
+		return ctx.Err()
+	}), BreakerThreshold(0.5, 1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	breaker.Run(ctx)
+	if state := breaker.State(); state != BreakerClosed {
+		t.Fatalf("expect state %v got %v", BreakerClosed, state)
+	}
+}
+
+func TestGroupCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	breaker := NewCircuitBreaker(Func(func(context.Context) error {
+		return errRun
+	}), BreakerThreshold(0.5, 1))
+	var logs []string
+	group := NewGroup(context.Background(), Log(func(info *LogInfo) {
+		logs = append(logs, info.String())
+	}))
+	if err := group.Go(breaker); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Wait(); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	found := false
+	for _, log := range logs {
+		if strings.HasSuffix(log, "circuit opens") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expect state change logged but got %v", logs)
+	}
+
+	// an open breaker fails fast without taking a goroutine of a busy pool,
+	// even if it is wrapped
+	pool := NewGoroutinePool(Max(1))
+	defer pool.Close()
+	release := make(chan struct{})
+	defer close(release)
+	if err := pool.Go(context.Background(), func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	for _, runner := range []Runner{breaker, run.WithTimeout(breaker, time.Hour)} {
+		group = NewGroup(context.Background(), Pool(pool))
+		if err := group.Go(runner); err != ErrCircuitOpen {
+			t.Fatalf("expect error %v got %v", ErrCircuitOpen, err)
+		}
+		if err := group.Wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBreakerWindowTooSmall(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect panic")
+		}
+	}()
+	BreakerWindow(breakerBuckets - 1)
+}
@@ -0,0 +1,84 @@
+package gopool
+
+import "sync"
+
+// budget is the goroutine budget of a pool specified by Max, shared by the
+// goroutines of the pool and its descendants. When a dispatching is blocked
+// on the budget, the idle goroutines of the descendants are asked to exit, so
+// that they do not hold the budget for the whole idle time.
+type budget struct {
+	slots chan struct{}
+
+	mu      sync.Mutex
+	waiting int                        // the dispatchings blocked on the budget
+	idle    map[chan struct{}]struct{} // the exit signals of the idle goroutines
+}
+
+func newBudget(n int) *budget {
+	return &budget{
+		slots: make(chan struct{}, n),
+		idle:  make(map[chan struct{}]struct{}),
+	}
+}
+
+// tryTake takes a slot of the budget without blocking
+func (b *budget) tryTake() bool {
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *budget) release() {
+	<-b.slots
+}
+
+// block marks a dispatching as blocked on the budget, and signals an idle
+// goroutine holding the budget to exit
+func (b *budget) block() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.waiting++
+	for exit := range b.idle {
+		delete(b.idle, exit)
+		signal(exit)
+		break
+	}
+}
+
+// unblock unmarks a dispatching blocked on the budget
+func (b *budget) unblock() {
+	b.mu.Lock()
+	b.waiting--
+	b.mu.Unlock()
+}
+
+// enterIdle registers the exit signal of an idle goroutine holding the
+// budget, it returns false if a dispatching is blocked on the budget, in
+// which case the goroutine should exit instead
+func (b *budget) enterIdle(exit chan struct{}) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.waiting > 0 {
+		return false
+	}
+	b.idle[exit] = struct{}{}
+	return true
+}
+
+// leaveIdle unregisters the exit signal of a goroutine no longer idle
+func (b *budget) leaveIdle(exit chan struct{}) {
+	b.mu.Lock()
+	delete(b.idle, exit)
+	b.mu.Unlock()
+}
+
+// signal sends to a buffered channel without blocking
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
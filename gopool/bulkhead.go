@@ -0,0 +1,241 @@
+package gopool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Quota specifies the share of a tenant in the slots of a pool, see Quotas
+type Quota struct {
+	// Reserve is the number of the slots reserved for the tenant, which the
+	// other tenants can never take
+	Reserve int
+	// Limit is the maximum number of the slots that the tenant can take, no
+	// limit if zero
+	Limit int
+}
+
+// Quotas returns the option to divide a GoroutinePool into bulkheads, so that
+// a noisy tenant cannot take every worker from the others.
+//
+// The pool runs up to size tasks at the same time, size should not exceed the
+// Max option if both are specified, or NewGoroutinePool panics. Each tenant,
+// identified by the tenant of the context passed to Go, see WithTenant and
+// Tenant, always has the slots reserved by its quota, and competes for the
+// unreserved slots up to its limit. The tenants not in quotas, including the
+// empty one, have no reserve and no limit. When the tenants are waiting for
+// the slots, they are served in round-robin order, and the tasks of a tenant
+// in FIFO order.
+func Quotas(size int, quotas map[string]Quota) PoolOption {
+	b := newBulkhead(size, quotas)
+	return func(p *GoroutinePool) {
+		p.bulkhead = b
+	}
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant of the tasks submitted
+// to a pool with it, see Quotas
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx, or an empty string if
+// there is none
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// Tenant specifies the tenant of a group, whose runners take the slots of the
+// pool by the quota of the tenant, see Quotas
+func Tenant(tenant string) GroupOption {
+	return func(g *Group) {
+		g.ctx = WithTenant(g.ctx, tenant)
+	}
+}
+
+// bulkhead admits the tasks of the tenants by their quotas
+type bulkhead struct {
+	size     int
+	reserved int
+	quotas   map[string]Quota
+
+	mu      sync.Mutex
+	shared  int // the slots taken beyond the reserves
+	tenants map[string]*tenantSlots
+	ring    []*tenantSlots // the tenants with waiters
+	next    int
+}
+
+type tenantSlots struct {
+	name    string
+	quota   Quota
+	inUse   int
+	waiters list.List // of chan struct{}
+}
+
+func newBulkhead(size int, quotas map[string]Quota) *bulkhead {
+	if size <= 0 {
+		panic("size should always be positive")
+	}
+	b := &bulkhead{
+		size:    size,
+		quotas:  make(map[string]Quota),
+		tenants: make(map[string]*tenantSlots),
+	}
+	for name, quota := range quotas {
+		if quota.Reserve < 0 || quota.Limit < 0 {
+			panic("quota should never be negative")
+		}
+		if quota.Limit > 0 && quota.Limit < quota.Reserve {
+			panic("limit should not be less than reserve")
+		}
+		b.reserved += quota.Reserve
+		b.quotas[name] = quota
+	}
+	if b.reserved > size {
+		panic("reserves should not exceed size")
+	}
+	return b
+}
+
+// acquire acquires a slot for tenant, blocking until it is available. It
+// returns ErrDispatchTimeout if ctx is cancelled and ErrClosed if quit is
+// closed before that.
+func (b *bulkhead) acquire(ctx context.Context, quit <-chan struct{}, tenant string) error {
+	b.mu.Lock()
+	t := b.tenant(tenant)
+	if t.waiters.Len() == 0 && b.eligible(t) {
+		b.take(t)
+		b.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := t.waiters.PushBack(ready)
+	if t.waiters.Len() == 1 {
+		b.ring = append(b.ring, t)
+	}
+	b.mu.Unlock()
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		err = ErrDispatchTimeout
+	case <-quit:
+		err = ErrClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-ready:
+		// acquired meanwhile, pretend the cancellation comes too late
+		return nil
+	default:
+	}
+	t.waiters.Remove(elem)
+	if t.waiters.Len() == 0 {
+		b.leaveRing(t)
+		b.drop(t)
+	}
+	return err
+}
+
+// release releases a slot of tenant and admits the waiters that fit
+func (b *bulkhead) release(tenant string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.tenants[tenant]
+	t.inUse--
+	if t.inUse >= t.quota.Reserve {
+		b.shared--
+	}
+	b.notify()
+	b.drop(t)
+}
+
+// notify admits the waiters of the eligible tenants in round-robin order
+func (b *bulkhead) notify() {
+	for len(b.ring) > 0 {
+		admitted := false
+		for i := 0; i < len(b.ring); i++ {
+			idx := (b.next + i) % len(b.ring)
+			t := b.ring[idx]
+			if !b.eligible(t) {
+				continue
+			}
+			b.take(t)
+			close(t.waiters.Remove(t.waiters.Front()).(chan struct{}))
+			if t.waiters.Len() == 0 {
+				b.ring = append(b.ring[:idx], b.ring[idx+1:]...)
+				b.next = idx
+			} else {
+				b.next = idx + 1
+			}
+			if len(b.ring) > 0 {
+				b.next %= len(b.ring)
+			} else {
+				b.next = 0
+			}
+			admitted = true
+			break
+		}
+		if !admitted {
+			return
+		}
+	}
+}
+
+// eligible returns if t can take a slot now
+func (b *bulkhead) eligible(t *tenantSlots) bool {
+	if t.quota.Limit > 0 && t.inUse >= t.quota.Limit {
+		return false
+	}
+	return t.inUse < t.quota.Reserve || b.shared < b.size-b.reserved
+}
+
+func (b *bulkhead) take(t *tenantSlots) {
+	if t.inUse >= t.quota.Reserve {
+		b.shared++
+	}
+	t.inUse++
+}
+
+// tenant returns the slots of a tenant, creating it if not exists
+func (b *bulkhead) tenant(name string) *tenantSlots {
+	t, ok := b.tenants[name]
+	if !ok {
+		t = &tenantSlots{name: name, quota: b.quotas[name]}
+		b.tenants[name] = t
+	}
+	return t
+}
+
+// drop removes the slots of an idle tenant
+func (b *bulkhead) drop(t *tenantSlots) {
+	if t.inUse == 0 && t.waiters.Len() == 0 {
+		delete(b.tenants, t.name)
+	}
+}
+
+func (b *bulkhead) leaveRing(t *tenantSlots) {
+	for i, rt := range b.ring {
+		if rt == t {
+			b.ring = append(b.ring[:i], b.ring[i+1:]...)
+			if b.next > i {
+				b.next--
+			}
+			if len(b.ring) > 0 {
+				b.next %= len(b.ring)
+			} else {
+				b.next = 0
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"h12.io/run"
+)
+
+func TestBulkheadReserve(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Quotas(4, map[string]Quota{
+		"quiet": {Reserve: 1},
+	}))
+	defer pool.Close()
+	noisy := WithTenant(context.Background(), "noisy")
+	quiet := WithTenant(context.Background(), "quiet")
+
+	// the noisy tenant takes every unreserved slot
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < 3; i++ {
+		if err := pool.Go(noisy, func() { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(noisy, 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+
+	// the quiet tenant still has its reserve
+	done := make(chan struct{})
+	if err := pool.Go(quiet, func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestBulkheadLimit(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Quotas(4, map[string]Quota{
+		"capped": {Limit: 2},
+	}))
+	defer pool.Close()
+	capped := WithTenant(context.Background(), "capped")
+
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < 2; i++ {
+		if err := pool.Go(capped, func() { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(capped, 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+
+	// the other tenants take the rest
+	for i := 0; i < 2; i++ {
+		if err := pool.Go(context.Background(), func() { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBulkheadRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	b := newBulkhead(1, nil)
+	if err := b.acquire(context.Background(), nil, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// tenant a queues 3 tasks before tenant b queues 3
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	for _, tenant := range []string{"a", "b"} {
+		for i := 0; i < 3; i++ {
+			tenant := tenant
+			ctx := newWaitingContext(context.Background())
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := b.acquire(ctx, nil, tenant); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				order = append(order, tenant)
+				mu.Unlock()
+				b.release(tenant)
+			}()
+			<-ctx.waiting
+		}
+	}
+	b.release("a")
+	wg.Wait()
+
+	expected := []string{"a", "b", "a", "b", "a", "b"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expect %v but got %v", expected, order)
+		}
+	}
+	if len(b.tenants) != 0 || len(b.ring) != 0 || b.shared != 0 {
+		t.Fatalf("expect the bulkhead reset but got %d tenants, %d in ring, %d shared", len(b.tenants), len(b.ring), b.shared)
+	}
+}
+
+func TestBulkheadCancel(t *testing.T) {
+	t.Parallel()
+
+	b := newBulkhead(1, nil)
+	if err := b.acquire(context.Background(), nil, "a"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	waitingCtx := newWaitingContext(ctx)
+	errChan := make(chan error, 1)
+	go func() { errChan <- b.acquire(waitingCtx, nil, "b") }()
+	<-waitingCtx.waiting
+	cancel()
+	if err := <-errChan; err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+	b.release("a")
+	if len(b.tenants) != 0 || len(b.ring) != 0 {
+		t.Fatalf("expect the bulkhead reset but got %d tenants, %d in ring", len(b.tenants), len(b.ring))
+	}
+}
+
+func TestBulkheadExceedMax(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect panic")
+		}
+	}()
+	NewGoroutinePool(Max(1), Quotas(2, nil))
+}
+
+func TestGroupTenant(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Quotas(2, map[string]Quota{
+		"a": {Limit: 1},
+	}))
+	defer pool.Close()
+
+	release := make(chan struct{})
+	g := NewGroup(context.Background(), Pool(pool), Tenant("a"))
+	if err := g.Go(run.Func(func(ctx context.Context) error {
+		<-release
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		if err := g.Go(run.Func(func(ctx context.Context) error { return nil })); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-dispatched:
+		t.Fatal("expect the second runner to wait for the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-dispatched
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
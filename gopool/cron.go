@@ -0,0 +1,202 @@
+package gopool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"h12.io/run"
+)
+
+// MissedError is logged with the Skip event when scheduled runs of a runner
+// are missed, either because the previous run is still running or because
+// the scheduler is late
+type MissedError struct {
+	Ticks int
+}
+
+// Error satisfies error interface
+func (e *MissedError) Error() string {
+	return fmt.Sprintf("%d tick(s) missed", e.Ticks)
+}
+
+// Cron is a runner that dispatches runners onto a pool according to their
+// cron schedules, see ParseSchedule for the syntax.
+//
+// A scheduled run is skipped if the previous run of the same runner is still
+// running. The start, exit and skip of the scheduled runners are logged by
+// the group running the Cron. Errors returned by the scheduled runners,
+// including recovered panics as PanicError, are logged but do not stop the
+// Cron, and so are the errors of dispatching them, which are logged with the
+// Skip event, except ErrClosed.
+type Cron struct {
+	pool     GroupPool
+	wakeChan chan struct{}
+
+	mu      sync.Mutex
+	entries []*cronEntry
+}
+
+type cronEntry struct {
+	schedule *Schedule
+	runner   Runner
+	next     time.Time
+	started  bool
+	running  bool
+}
+
+// NewCron creates a new Cron dispatching runners onto pool, if pool is nil,
+// new goroutines are started instead
+func NewCron(pool GroupPool) *Cron {
+	if pool == nil {
+		pool = dummyPool{}
+	}
+	return &Cron{
+		pool:     pool,
+		wakeChan: make(chan struct{}, 1),
+	}
+}
+
+// Add schedules runner with the cron expression, it can be called before or
+// while the Cron is running
+func (c *Cron) Add(expr string, runner Runner) error {
+	schedule, err := ParseSchedule(expr)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.entries = append(c.entries, &cronEntry{schedule: schedule, runner: runner})
+	c.mu.Unlock()
+	select {
+	case c.wakeChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run dispatches the scheduled runners until ctx is cancelled, and then waits
+// for the dispatched runners to exit and returns nil, or until the pool is
+// closed, and then returns ErrClosed after the wait. The time is measured by
+// the clock from run.ClockFromContext.
+func (c *Cron) Run(ctx context.Context) error {
+	clock := run.ClockFromContext(ctx)
+	logFunc := logFuncFromContext(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		now := clock.Now()
+		next, err := c.dispatch(ctx, now, logFunc, &wg)
+		if err != nil {
+			return err
+		}
+		var timer run.Timer
+		var timerChan <-chan time.Time
+		if !next.IsZero() {
+			timer = clock.NewTimer(next.Sub(now))
+			timerChan = timer.C()
+		}
+		select {
+		case <-timerChan:
+		case <-c.wakeChan:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// dispatch dispatches the runners due at now, and returns the time of the
+// next scheduled run, or ErrClosed if the pool is closed
+func (c *Cron) dispatch(ctx context.Context, now time.Time, logFunc func(*LogInfo), wg *sync.WaitGroup) (next time.Time, _ error) {
+	c.mu.Lock()
+	var due []*cronEntry
+	for _, e := range c.entries {
+		if !e.started {
+			e.started = true
+			e.next = e.schedule.Next(now)
+		}
+		if e.next.IsZero() {
+			continue
+		}
+		if !e.next.After(now) {
+			missed := 0
+			for e.next = e.schedule.Next(e.next); !e.next.IsZero() && !e.next.After(now); e.next = e.schedule.Next(e.next) {
+				missed++
+			}
+			if e.running {
+				missed++
+			} else {
+				e.running = true
+				due = append(due, e)
+			}
+			if missed > 0 && logFunc != nil {
+				logFunc(&LogInfo{
+					Runner: e.runner,
+					Event:  Skip,
+					Err:    &MissedError{Ticks: missed},
+				})
+			}
+		}
+		if !e.next.IsZero() && (next.IsZero() || e.next.Before(next)) {
+			next = e.next
+		}
+	}
+	c.mu.Unlock()
+
+	for _, e := range due {
+		e := e
+		wg.Add(1)
+		if err := c.pool.Go(ctx, func() {
+			defer wg.Done()
+			c.run(ctx, e.runner, logFunc)
+			c.mu.Lock()
+			e.running = false
+			c.mu.Unlock()
+		}); err != nil {
+			wg.Done()
+			c.mu.Lock()
+			e.running = false
+			c.mu.Unlock()
+			if err == ErrClosed {
+				return next, err
+			}
+			if ctx.Err() == nil && logFunc != nil {
+				logFunc(&LogInfo{
+					Runner: e.runner,
+					Event:  Skip,
+					Err:    err,
+				})
+			}
+		}
+	}
+	return next, nil
+}
+
+// run runs a scheduled runner and logs its start and exit
+func (c *Cron) run(ctx context.Context, runner Runner, logFunc func(*LogInfo)) {
+	if logFunc != nil {
+		logFunc(&LogInfo{
+			Runner: runner,
+			Event:  Start,
+		})
+	}
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+		if logFunc != nil {
+			logFunc(&LogInfo{
+				Runner: runner,
+				Event:  Exit,
+				Err:    err,
+			})
+		}
+	}()
+	err = runner.Run(ctx)
+}
@@ -0,0 +1,136 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestCron(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Date(2021, 4, 16, 10, 30, 15, 0, time.UTC))
+	var (
+		mu   sync.Mutex
+		logs []*LogInfo
+	)
+	group := NewGroup(run.WithClock(context.Background(), clock), Log(func(info *LogInfo) {
+		mu.Lock()
+		logs = append(logs, info)
+		mu.Unlock()
+	}))
+	pool := NewGoroutinePool()
+	defer pool.Close()
+	cron := NewCron(pool)
+	runner := &runtest.FakeRunner{Block: true}
+	if err := cron.Add("* * * * *", runner); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Go(cron); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(45 * time.Second)
+	<-runner.Started()
+
+	// still running at the next tick
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(1)
+	runner.Release()
+
+	group.Cancel()
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if calls := runner.Calls(); calls != 1 {
+		t.Fatalf("expect 1 call but got %d", calls)
+	}
+	var skipped *LogInfo
+	for _, info := range logs {
+		if info.Event == Skip {
+			skipped = info
+		}
+	}
+	if skipped == nil || skipped.Err.(*MissedError).Ticks != 1 {
+		t.Fatalf("expect 1 tick missed but got %v", skipped)
+	}
+}
+
+func TestCronDispatchError(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Date(2021, 4, 16, 10, 30, 15, 0, time.UTC))
+	var (
+		mu   sync.Mutex
+		logs []*LogInfo
+	)
+	group := NewGroup(run.WithClock(context.Background(), clock), Log(func(info *LogInfo) {
+		mu.Lock()
+		logs = append(logs, info)
+		mu.Unlock()
+	}))
+	cron := NewCron(failingPool{err: ErrRateLimited})
+	runner := &runtest.FakeRunner{}
+	if err := cron.Add("* * * * *", runner); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Go(cron); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failure is logged, and the Cron keeps running
+	clock.BlockUntil(1)
+	clock.Advance(45 * time.Second)
+	clock.BlockUntil(1)
+	group.Cancel()
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if calls := runner.Calls(); calls != 0 {
+		t.Fatalf("expect no calls but got %d", calls)
+	}
+	var skipped *LogInfo
+	for _, info := range logs {
+		if info.Event == Skip {
+			skipped = info
+		}
+	}
+	if skipped == nil || skipped.Err != ErrRateLimited {
+		t.Fatalf("expect skip with %v but got %v", ErrRateLimited, skipped)
+	}
+}
+
+func TestCronPoolClosed(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Date(2021, 4, 16, 10, 30, 15, 0, time.UTC))
+	ctx := run.WithClock(context.Background(), clock)
+	pool := NewGoroutinePool()
+	pool.Close()
+	cron := NewCron(pool)
+	if err := cron.Add("* * * * *", &runtest.FakeRunner{}); err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error)
+	go func() { errChan <- cron.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(45 * time.Second)
+	if err := <-errChan; err != ErrClosed {
+		t.Fatalf("expect %v but got %v", ErrClosed, err)
+	}
+}
+
+// failingPool fails every dispatching with err
+type failingPool struct {
+	err error
+}
+
+func (p failingPool) Go(ctx context.Context, fn func()) error {
+	return p.err
+}
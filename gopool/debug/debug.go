@@ -0,0 +1,76 @@
+// Package debug serves the runners currently executing in any gopool.Group or
+// gopool.GoroutinePool via its HTTP server, in the manner of net/http/pprof.
+//
+// The package is typically only imported for the side effect of registering
+// its HTTP handler at /debug/run and enabling gopool.TrackRunners:
+//
+//	import _ "h12.io/run/gopool/debug"
+//
+// The page is rendered as plain text by default, the query parameter
+// format=json switches to JSON and stack=1 includes the stack of the
+// goroutine executing each runner, e.g.
+//
+//	curl 'http://localhost:6060/debug/run?format=json&stack=1'
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"h12.io/run/gopool"
+)
+
+func init() {
+	gopool.TrackRunners(true)
+	http.Handle("/debug/run", Handler())
+}
+
+// Handler returns an HTTP handler that serves the runners currently
+// executing in any gopool.Group or gopool.GoroutinePool
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	stack := r.FormValue("stack") == "1"
+	runners := gopool.Runners(stack)
+	if r.FormValue("format") == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(runners); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writeText(w, runners, stack)
+}
+
+func writeText(w http.ResponseWriter, runners []*gopool.RunnerInfo, stack bool) {
+	fmt.Fprintf(w, "runners: %d\n\n", len(runners))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "GOROUTINE\tGROUP\tSTATE\tELAPSED\tNAME")
+	for _, info := range runners {
+		group := info.Group
+		if group == "" {
+			group = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			info.GoroutineID,
+			group,
+			info.State,
+			info.Elapsed.Round(time.Millisecond),
+			info.Name,
+		)
+	}
+	tw.Flush()
+	if stack {
+		for _, info := range runners {
+			fmt.Fprintf(w, "\n%s\n", info.Stack)
+		}
+	}
+}
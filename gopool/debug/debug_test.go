@@ -0,0 +1,65 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"h12.io/run/gopool"
+)
+
+type blockingRunner struct {
+	started chan struct{}
+	quit    chan struct{}
+}
+
+func (r blockingRunner) Name() string { return "debug test runner" }
+
+func (r blockingRunner) Run(ctx context.Context) error {
+	close(r.started)
+	<-r.quit
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	group := gopool.NewGroup(context.Background(), gopool.Name("debug group"))
+	runner := blockingRunner{started: make(chan struct{}), quit: make(chan struct{})}
+	if err := group.Go(runner); err != nil {
+		t.Fatal(err)
+	}
+	defer group.Wait()
+	defer close(runner.quit)
+	<-runner.started
+
+	t.Run("text", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Handler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/run", nil))
+		body := w.Body.String()
+		if !strings.Contains(body, "debug test runner") || !strings.Contains(body, "debug group") {
+			t.Fatalf("expect the runner listed but got\n%s", body)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		Handler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/run?format=json&stack=1", nil))
+		var runners []*gopool.RunnerInfo
+		if err := json.Unmarshal(w.Body.Bytes(), &runners); err != nil {
+			t.Fatal(err)
+		}
+		var found *gopool.RunnerInfo
+		for _, info := range runners {
+			if info.Name == "debug test runner" {
+				found = info
+			}
+		}
+		if found == nil {
+			t.Fatalf("expect the runner listed but got\n%s", w.Body.String())
+		}
+		if !strings.Contains(found.Stack, "blockingRunner") {
+			t.Fatalf("expect the stack of the runner but got %s", found.Stack)
+		}
+	})
+}
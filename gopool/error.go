@@ -1,6 +1,9 @@
 package gopool
 
-import "fmt"
+import (
+	"fmt"
+	"runtime"
+)
 
 // PanicError represents recovered panic info
 type PanicError struct {
@@ -12,3 +15,13 @@ type PanicError struct {
 func (e *PanicError) Error() string {
 	return fmt.Sprintf("%v\n%s", e.Err, e.Stack)
 }
+
+// NewPanicError creates a PanicError from the recovered value r, with the
+// stack of the current goroutine, it should be called in the deferred
+// function recovering the panic
+func NewPanicError(r interface{}) *PanicError {
+	const size = 64 << 10
+	buf := make([]byte, size)
+	buf = buf[:runtime.Stack(buf, false)]
+	return &PanicError{Err: r, Stack: buf}
+}
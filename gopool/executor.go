@@ -0,0 +1,48 @@
+package gopool
+
+import "context"
+
+// InlinePool is a GroupPool running each task synchronously on the calling
+// goroutine, so that the tasks run one by one in a deterministic order, e.g.
+// in tests. Note that Group.Go does not return until the runner exits.
+type InlinePool struct{}
+
+// Go runs fn and returns nil after it returns. It returns ErrDispatchTimeout
+// without running fn if ctx is already cancelled.
+func (InlinePool) Go(ctx context.Context, fn func()) error {
+	if ctx.Err() != nil {
+		return ErrDispatchTimeout
+	}
+	fn()
+	return nil
+}
+
+// BoundedPool is a GroupPool starting a new goroutine for each task without
+// reusing it, while limiting the number of the running tasks
+type BoundedPool struct {
+	sem chan struct{}
+}
+
+// NewBoundedPool creates a new BoundedPool running at most n tasks at the same
+// time
+func NewBoundedPool(n int) *BoundedPool {
+	if n <= 0 {
+		panic("n should always be positive")
+	}
+	return &BoundedPool{sem: make(chan struct{}, n)}
+}
+
+// Go starts fn in a new goroutine, and blocks if n tasks are running. It
+// returns ErrDispatchTimeout if ctx is cancelled when waiting.
+func (p *BoundedPool) Go(ctx context.Context, fn func()) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ErrDispatchTimeout
+	}
+	go func() {
+		defer func() { <-p.sem }()
+		fn()
+	}()
+	return nil
+}
@@ -0,0 +1,89 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h12.io/run"
+)
+
+func TestInlinePoolGroup(t *testing.T) {
+	t.Parallel()
+
+	var order []int
+	g := NewGroup(context.Background(), Pool(InlinePool{}))
+	for i := 0; i < 3; i++ {
+		i := i
+		if err := g.Go(run.Func(func(context.Context) error {
+			order = append(order, i)
+			return nil
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range order {
+		if i != v {
+			t.Fatalf("expect runners in order but got %v", order)
+		}
+	}
+
+	errFail := errors.New("fail")
+	g = NewGroup(context.Background(), Pool(InlinePool{}))
+	if err := g.Go(run.Func(func(context.Context) error { return errFail })); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Go(run.Func(func(context.Context) error { return nil })); err != errFail {
+		t.Fatalf("expect %v but got %v", errFail, err)
+	}
+}
+
+func TestBoundedPool(t *testing.T) {
+	t.Parallel()
+
+	const n = 3
+	pool := NewBoundedPool(n)
+	var running, peak int64
+	g := NewGroup(context.Background(), Pool(pool))
+	for i := 0; i < 30; i++ {
+		if err := g.Go(run.Func(func(context.Context) error {
+			cur := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if cur <= p || atomic.CompareAndSwapInt64(&peak, p, cur) {
+					break
+				}
+			}
+			runtime.Gosched()
+			return nil
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak > n {
+		t.Fatalf("expect at most %d running tasks but got %d", n, peak)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < n; i++ {
+		if err := pool.Go(context.Background(), func() { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+}
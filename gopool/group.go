@@ -2,8 +2,11 @@ package gopool
 
 import (
 	"context"
-	"runtime"
 	"sync"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/internal/goroutine"
 )
 
 // Group combines multiple concurrent tasks into one
@@ -11,9 +14,22 @@ type Group struct {
 	ctx    context.Context
 	cancel func()
 	pool   GroupPool
-
-	logFunc func(info *LogInfo)
-	recover bool
+	name   string
+	clock  run.Clock
+
+	logFunc  func(info *LogInfo)
+	recover  bool
+	watchdog *watchdog
+	liveness *liveness
+	timeout  time.Duration
+	limiter  *limiter
+	dedupKey func(runner Runner) string
+
+	mu          sync.Mutex
+	members     map[*member]struct{}
+	seen        map[string]struct{}
+	spawned     []Runner // the queue of the spawned runners, see Spawn
+	dispatching bool     // the spawned runners are being dispatched
 
 	wg      sync.WaitGroup
 	errOnce sync.Once
@@ -28,11 +44,22 @@ type GroupPool interface {
 // GroupOption is used to specify an option for Group
 type GroupOption func(*Group)
 
-// Pool specifies the goroutine pool for a group, if not set, a dummy
-// implementation is used (always starting new goroutines)
-func Pool(p *GoroutinePool) GroupOption {
+// Pool specifies the goroutine pool for a group, which can be a GoroutinePool,
+// an InlinePool, a BoundedPool or any other GroupPool implementation. If not
+// set, a dummy implementation is used (always starting new goroutines)
+func Pool(p GroupPool) GroupOption {
+	return func(g *Group) {
+		if p != nil {
+			g.pool = p
+		}
+	}
+}
+
+// Name specifies the name of a group, which is shown along with its runners by
+// Runners
+func Name(name string) GroupOption {
 	return func(g *Group) {
-		g.pool = p
+		g.name = name
 	}
 }
 
@@ -52,25 +79,43 @@ func Recover(yes bool) GroupOption {
 	}
 }
 
-// NewGroup creates a new Group
+// Timeout specifies the default timeout of each runner in a group, see
+// run.WithTimeout
+func Timeout(timeout time.Duration) GroupOption {
+	return func(g *Group) {
+		g.timeout = timeout
+	}
+}
+
+// NewGroup creates a new Group, whose timers are measured by the clock from
+// run.ClockFromContext(ctx)
 func NewGroup(ctx context.Context, options ...GroupOption) *Group {
 	ctx, cancel := context.WithCancel(ctx)
 	g := &Group{
 		ctx:     ctx,
 		cancel:  cancel,
 		pool:    dummyPool{},
+		clock:   run.ClockFromContext(ctx),
 		recover: false,
 	}
 	for _, opt := range options {
 		opt(g)
 	}
+	if g.logFunc != nil {
+		g.ctx = withLogFunc(g.ctx, g.logFunc)
+	}
+	g.ctx = run.WithGroup(g.ctx, g)
 	return g
 }
 
 // Go runs the given runner in the internal goroutine pool.
 // It returns nil when the goroutine is dispatched successfully.
 // It returns ErrDispatchTimeout if the context of the group is cancelled when
-// waiting for an idle goroutine to be available.
+// waiting for an idle goroutine or a token of the rate limit to be available.
+// It returns ErrRateLimited if the rate limit fails fast.
+// It returns the error of Admit without dispatching if the runner is a
+// run.Admitter rejecting the run, e.g. ErrCircuitOpen of a CircuitBreaker.
+// It returns nil without dispatching if the runner is a duplicate, see Dedup.
 // The first error return from a runner cancels the group, and all subsequent
 // calls to Go as well as Wait will return the error
 func (g *Group) Go(runner Runner) error {
@@ -79,46 +124,114 @@ func (g *Group) Go(runner Runner) error {
 		return g.Wait()
 	default:
 	}
+	return g.dispatch(runner)
+}
+
+// dispatch dispatches the runner onto the pool unless it is a duplicate
+func (g *Group) dispatch(runner Runner) error {
+	if g.duplicate(runner) {
+		return nil
+	}
+	return g.start(runner)
+}
+
+// start dispatches the runner onto the pool, the key of a runner failing to
+// be dispatched is forgotten, so that it can be submitted again, see Dedup
+func (g *Group) start(runner Runner) error {
+	m, err := g.admit(runner)
+	if err != nil {
+		g.forget(runner)
+		return err
+	}
+	err = g.pool.Go(g.ctx, func() {
+		g.runMember(m, runner)
+		g.runSpawned()
+	})
+	if err != nil {
+		g.leave(m, nil)
+		g.wg.Done()
+		g.forget(runner)
+	}
+	return err
+}
+
+// admit makes the runner a member of the group unless the runner rejects the
+// run or the rate limit fails
+func (g *Group) admit(runner Runner) (*member, error) {
+	if a, ok := runner.(run.Admitter); ok {
+		if err := a.Admit(g.ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.limiter != nil {
+		if err := g.limiter.wait(g.ctx, g.clock, g.limiter.name(runner)); err != nil {
+			return nil, err
+		}
+	}
 
+	m := g.join(runner)
 	g.wg.Add(1)
-	err := g.pool.Go(g.ctx, func() {
+	return m, nil
+}
+
+// runMember runs the runner admitted as member m on the current goroutine
+func (g *Group) runMember(m *member, runner Runner) {
+	track := trackingRunners()
+	var gid uint64
+	if track || g.watchdog != nil || g.liveness != nil {
+		gid = goroutine.ID()
+	}
+	var e *entry
+	if track {
+		e = registry.enter(gid, runner, g.name, g.ctx)
+	}
+	if g.logFunc != nil {
+		g.logFunc(&LogInfo{
+			Runner: runner,
+			Event:  Start,
+		})
+	}
+
+	var err error
+	defer func() {
+		if g.recover {
+			if r := recover(); r != nil {
+				err = NewPanicError(r)
+			}
+		}
+		g.leave(m, err)
+		if err != nil {
+			g.setErrOnce(err)
+			g.cancel()
+		}
 		if g.logFunc != nil {
 			g.logFunc(&LogInfo{
 				Runner: runner,
-				Event:  Start,
+				Event:  Exit,
+				Err:    err,
 			})
 		}
+		registry.exit(e)
+		g.wg.Done()
+	}()
 
-		var err error
-		defer func() {
-			if g.recover {
-				if r := recover(); r != nil {
-					const size = 64 << 10
-					buf := make([]byte, size)
-					buf = buf[:runtime.Stack(buf, false)]
-					err = &PanicError{Err: r, Stack: buf}
-				}
-			}
-			if err != nil {
-				g.setErrOnce(err)
-				g.cancel()
-			}
-			if g.logFunc != nil {
-				g.logFunc(&LogInfo{
-					Runner: runner,
-					Event:  Exit,
-					Err:    err,
-				})
-			}
-			g.wg.Done()
-		}()
+	if g.watchdog != nil {
+		defer g.watchdog.watch(g.ctx, g.clock, runner, gid)()
+	}
+	if g.liveness != nil {
+		err = g.runLive(runner, gid)
+	} else {
+		err = g.runOnce(g.ctx, runner)
+	}
+}
 
-		err = runner.Run(g.ctx)
-	})
-	if err == ErrDispatchTimeout {
-		g.wg.Done()
+// runOnce runs the runner with the default timeout applied
+func (g *Group) runOnce(ctx context.Context, runner Runner) error {
+	if g.timeout > 0 {
+		return run.WithTimeout(runner, g.timeout).Run(ctx)
 	}
-	return err
+	return runner.Run(ctx)
 }
 
 // Cancel cancels the group
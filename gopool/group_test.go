@@ -6,6 +6,9 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
+
+	"h12.io/run"
 )
 
 func TestGroupGoExactlyOnce(t *testing.T) {
@@ -120,3 +123,63 @@ func TestGroupRecoverPanic(t *testing.T) {
 	}
 
 }
+
+func TestGroupTimeout(t *testing.T) {
+	t.Parallel()
+
+	group := NewGroup(context.Background(), Timeout(time.Millisecond))
+	if err := group.Go(Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})); err != nil {
+		t.Fatal(err)
+	}
+	var timeoutErr *run.TimeoutError
+	if err := group.Wait(); !errors.As(err, &timeoutErr) {
+		t.Fatalf("expect *run.TimeoutError got %v", err)
+	}
+}
+
+func TestGroupDispatchError(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		pool *GoroutinePool
+		err  error
+	}{
+		{
+			name: "closed",
+			pool: func() *GoroutinePool {
+				pool := NewGoroutinePool()
+				pool.Close()
+				return pool
+			}(),
+			err: ErrClosed,
+		},
+		{
+			name: "rate limited",
+			pool: NewGoroutinePool(PoolRateLimit(Limit{Rate: 1e-9, Burst: 1, FailFast: true})),
+			err:  ErrRateLimited,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			defer tc.pool.Close()
+			group := NewGroup(context.Background(), Pool(tc.pool))
+			if tc.err == ErrRateLimited {
+				if err := group.Go(Func(func(context.Context) error { return nil })); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := group.Go(Func(func(context.Context) error { return nil })); err != tc.err {
+				t.Fatalf("expect %v but got %v", tc.err, err)
+			}
+			// a failed dispatch is not waited for
+			if err := group.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
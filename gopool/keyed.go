@@ -0,0 +1,120 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedExecutor runs tasks on a pool, so that the tasks submitted with the
+// same key run one at a time in the order of submission, while the tasks of
+// different keys run concurrently, up to the Max of the pool.
+//
+// The tasks of a key are run by one goroutine of the pool until its queue is
+// drained, then the key is removed, so idle keys do not take any resource.
+type KeyedExecutor struct {
+	pool GroupPool
+
+	mu     sync.Mutex
+	queues map[string]*keyQueue
+}
+
+type keyQueue struct {
+	key         string
+	tasks       []func()
+	dispatching chan struct{} // closed when the dispatching of a goroutine is done
+}
+
+// NewKeyedExecutor creates a new KeyedExecutor running tasks on pool, if pool
+// is nil, new goroutines are started instead
+func NewKeyedExecutor(pool GroupPool) *KeyedExecutor {
+	if pool == nil {
+		pool = dummyPool{}
+	}
+	return &KeyedExecutor{
+		pool:   pool,
+		queues: make(map[string]*keyQueue),
+	}
+}
+
+// Go submits fn to run after the tasks submitted earlier with the same key.
+// If no goroutine is running the tasks of the key, one is dispatched from the
+// pool, and the error of the dispatching is returned, e.g.
+// ErrDispatchTimeout if ctx is cancelled when waiting for an idle goroutine.
+// Otherwise fn is queued and nil is returned.
+//
+// While a goroutine is being dispatched for the key, Go waits for the
+// dispatching, and dispatches a goroutine itself if that fails, so that nil
+// is returned only if fn is queued for a running goroutine.
+func (e *KeyedExecutor) Go(ctx context.Context, key string, fn func()) error {
+	e.mu.Lock()
+	for {
+		q, ok := e.queues[key]
+		if !ok {
+			break
+		}
+		if q.dispatching == nil {
+			q.tasks = append(q.tasks, fn)
+			e.mu.Unlock()
+			return nil
+		}
+		dispatching := q.dispatching
+		e.mu.Unlock()
+		select {
+		case <-dispatching:
+		case <-ctx.Done():
+			return ErrDispatchTimeout
+		}
+		e.mu.Lock()
+	}
+	q := &keyQueue{
+		key:         key,
+		tasks:       []func(){fn},
+		dispatching: make(chan struct{}),
+	}
+	e.queues[key] = q
+	e.mu.Unlock()
+
+	err := e.pool.Go(ctx, func() { e.drain(q) })
+
+	e.mu.Lock()
+	if err != nil {
+		q.tasks = nil
+		e.remove(q)
+	}
+	close(q.dispatching)
+	q.dispatching = nil
+	e.mu.Unlock()
+	return err
+}
+
+// drain runs the tasks of q until it is empty, then removes q
+func (e *KeyedExecutor) drain(q *keyQueue) {
+	for {
+		e.mu.Lock()
+		if len(q.tasks) == 0 {
+			e.remove(q)
+			e.mu.Unlock()
+			return
+		}
+		fn := q.tasks[0]
+		q.tasks[0] = nil
+		q.tasks = q.tasks[1:]
+		e.mu.Unlock()
+
+		fn()
+	}
+}
+
+// remove removes q unless it has been replaced
+func (e *KeyedExecutor) remove(q *keyQueue) {
+	if e.queues[q.key] == q {
+		delete(e.queues, q.key)
+	}
+}
+
+// Keys returns the number of the keys with running or queued tasks
+func (e *KeyedExecutor) Keys() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.queues)
+}
@@ -0,0 +1,147 @@
+package gopool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestKeyedExecutor(t *testing.T) {
+	t.Parallel()
+
+	const (
+		keys  = 10
+		tasks = 100
+		max   = 4
+	)
+	pool := NewGoroutinePool(Max(max))
+	defer pool.Close()
+	executor := NewKeyedExecutor(pool)
+
+	var (
+		mu      sync.Mutex
+		results = make(map[string][]int)
+		running int64
+		peak    int64
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < tasks; i++ {
+		for k := 0; k < keys; k++ {
+			i, key := i, fmt.Sprint(k)
+			wg.Add(1)
+			if err := executor.Go(context.Background(), key, func() {
+				defer wg.Done()
+				n := atomic.AddInt64(&running, 1)
+				defer atomic.AddInt64(&running, -1)
+				for {
+					p := atomic.LoadInt64(&peak)
+					if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+						break
+					}
+				}
+				mu.Lock()
+				results[key] = append(results[key], i)
+				mu.Unlock()
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	wg.Wait()
+
+	for key, seq := range results {
+		if len(seq) != tasks {
+			t.Fatalf("expect %d tasks of key %s but got %d", tasks, key, len(seq))
+		}
+		for i, v := range seq {
+			if i != v {
+				t.Fatalf("expect tasks of key %s run in order but got %v", key, seq)
+			}
+		}
+	}
+	if peak > max {
+		t.Fatalf("expect at most %d tasks running concurrently but got %d", max, peak)
+	}
+	// the last task is done before its key is removed, Close waits for it
+	pool.Close()
+	if n := executor.Keys(); n != 0 {
+		t.Fatalf("expect no keys but got %d", n)
+	}
+}
+
+func TestKeyedExecutorSerial(t *testing.T) {
+	t.Parallel()
+
+	executor := NewKeyedExecutor(nil)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var ran int64
+	executor.Go(context.Background(), "a", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	done := make(chan struct{})
+	executor.Go(context.Background(), "a", func() {
+		atomic.AddInt64(&ran, 1)
+		close(done)
+	})
+	// a different key is not blocked
+	other := make(chan struct{})
+	executor.Go(context.Background(), "b", func() { close(other) })
+	<-other
+	if atomic.LoadInt64(&ran) != 0 {
+		t.Fatal("expect the second task of the same key to wait for the first")
+	}
+	close(release)
+	<-done
+}
+
+func TestKeyedExecutorDispatchError(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool()
+	pool.Close()
+	executor := NewKeyedExecutor(pool)
+	if err := executor.Go(context.Background(), "a", func() {}); err != ErrClosed {
+		t.Fatalf("expect error %v got %v", ErrClosed, err)
+	}
+	if n := executor.Keys(); n != 0 {
+		t.Fatalf("expect no keys but got %d", n)
+	}
+}
+
+func TestKeyedExecutorDispatchWait(t *testing.T) {
+	t.Parallel()
+
+	pool := &stallingPool{stalled: make(chan struct{})}
+	executor := NewKeyedExecutor(pool)
+	var ran int64
+
+	// the first task stalls in dispatching until its ctx is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error)
+	go func() { errChan <- executor.Go(ctx, "a", func() { t.Error("expect the failed task dropped") }) }()
+	<-pool.stalled
+
+	// the second task waits for the dispatching, and then dispatches itself
+	waitingCtx := newWaitingContext(context.Background())
+	queued := make(chan error)
+	go func() { queued <- executor.Go(waitingCtx, "a", func() { atomic.AddInt64(&ran, 1) }) }()
+	<-waitingCtx.waiting
+	cancel()
+	if err := <-errChan; err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+	if err := <-queued; err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&ran); n != 1 {
+		t.Fatalf("expect the second task run once but got %d", n)
+	}
+	if n := executor.Keys(); n != 0 {
+		t.Fatalf("expect no keys but got %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+
+	"h12.io/run"
+)
+
+// member tracks a runner that is a run.Readier or a run.Stopper while it is
+// running in a group
+type member struct {
+	runner Runner
+	exited chan struct{}
+	err    error
+}
+
+// join tracks runner if it is a run.Readier or a run.Stopper, otherwise nil
+// is returned
+func (g *Group) join(runner Runner) *member {
+	_, readier := runner.(run.Readier)
+	_, stopper := runner.(run.Stopper)
+	if !readier && !stopper {
+		return nil
+	}
+	m := &member{runner: runner, exited: make(chan struct{})}
+	g.mu.Lock()
+	if g.members == nil {
+		g.members = make(map[*member]struct{})
+	}
+	g.members[m] = struct{}{}
+	g.mu.Unlock()
+	return m
+}
+
+// leave stops tracking m after its runner exits with err
+func (g *Group) leave(m *member, err error) {
+	if m == nil {
+		return
+	}
+	g.mu.Lock()
+	delete(g.members, m)
+	g.mu.Unlock()
+	m.err = err
+	close(m.exited)
+}
+
+func (g *Group) snapshot() []*member {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	members := make([]*member, 0, len(g.members))
+	for m := range g.members {
+		members = append(members, m)
+	}
+	return members
+}
+
+// WaitReady waits for the running runners that are run.Readier to be ready.
+// A runner exiting with nil before being ready is ignored, while its error is
+// returned if it exits with an error. If ctx or the context of the group is
+// done first, its error is returned.
+func (g *Group) WaitReady(ctx context.Context) error {
+	for _, m := range g.snapshot() {
+		r, ok := m.runner.(run.Readier)
+		if !ok {
+			continue
+		}
+		select {
+		case <-r.Ready():
+		case <-m.exited:
+			if m.err != nil {
+				return m.err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.ctx.Done():
+			return g.ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Stop stops the group in two phases: the Stop method of each running runner
+// that is a run.Stopper is called concurrently to drain gracefully, and after
+// all of them return, the group is cancelled. It then waits for all the
+// runners to exit and returns the first error like Wait, or ctx.Err() if ctx
+// is done first.
+//
+// The errors returned by the Stop methods are ignored, because the runners
+// report their errors when they exit.
+func (g *Group) Stop(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, m := range g.snapshot() {
+		if s, ok := m.runner.(run.Stopper); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.Stop(ctx)
+			}()
+		}
+	}
+	wg.Wait()
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return g.Wait()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
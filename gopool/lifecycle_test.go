@@ -0,0 +1,112 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"h12.io/run/runtest"
+)
+
+// serviceRunner is ready once it runs, and exits when it is drained or
+// cancelled
+type serviceRunner struct {
+	ready   chan struct{}
+	drain   chan struct{}
+	drained bool
+}
+
+func newServiceRunner() *serviceRunner {
+	return &serviceRunner{ready: make(chan struct{}), drain: make(chan struct{})}
+}
+
+func (s *serviceRunner) Ready() <-chan struct{} { return s.ready }
+
+func (s *serviceRunner) Stop(ctx context.Context) error {
+	close(s.drain)
+	return nil
+}
+
+func (s *serviceRunner) Run(ctx context.Context) error {
+	close(s.ready)
+	select {
+	case <-s.drain:
+		s.drained = true
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestGroupWaitReady(t *testing.T) {
+	t.Parallel()
+
+	group := NewGroup(context.Background())
+	services := []*serviceRunner{newServiceRunner(), newServiceRunner()}
+	for _, s := range services {
+		if err := group.Go(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := group.Go(&runtest.FakeRunner{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.WaitReady(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Stop(context.Background()); err != context.Canceled {
+		t.Fatalf("expect error %v from the runner without Stop but got %v", context.Canceled, err)
+	}
+	for i, s := range services {
+		if !s.drained {
+			t.Fatalf("expect service %d drained before cancellation", i)
+		}
+	}
+}
+
+func TestGroupWaitReadyError(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	group := NewGroup(context.Background())
+	if err := group.Go(&failingService{err: errRun}); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.WaitReady(context.Background()); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+	if err := group.Wait(); err != errRun {
+		t.Fatalf("expect error %v got %v", errRun, err)
+	}
+}
+
+func TestGroupStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	group := NewGroup(context.Background())
+	if err := group.Go(Func(func(context.Context) error {
+		<-release // ignore cancellation
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := group.Stop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect error %v got %v", context.DeadlineExceeded, err)
+	}
+	close(release)
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingService fails before being ready
+type failingService struct {
+	err error
+}
+
+func (s *failingService) Ready() <-chan struct{}        { return make(chan struct{}) }
+func (s *failingService) Run(ctx context.Context) error { return s.err }
@@ -0,0 +1,124 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/internal/goroutine"
+)
+
+// ErrHung is returned by a runner cancelled by the liveness check of a group
+var ErrHung = errors.New("runner hung without heartbeat")
+
+// HungAction enum specifies what a group does with a hung runner
+type HungAction int
+
+// HungAction constants
+const (
+	ReportHung  HungAction = iota // only report the hung runner
+	CancelHung                    // cancel the hung runner and fail the group with ErrHung
+	RestartHung                   // cancel the hung runner and run it again
+)
+
+// Liveness specifies heartbeat based liveness checking for a group. Every
+// runner in the group is expected to call run.Heartbeat within deadline since
+// it starts or since its last heartbeat, otherwise it is considered hung,
+// reported to alert and then handled according to action.
+//
+// A hung runner is restarted only after it returns from the cancellation. The
+// deadline is measured by the clock from run.ClockFromContext of the group
+// context.
+func Liveness(deadline time.Duration, action HungAction, alert func(*Alert)) GroupOption {
+	if deadline <= 0 {
+		panic("heartbeat deadline should always be positive")
+	}
+	return func(g *Group) {
+		g.liveness = &liveness{
+			deadline: deadline,
+			action:   action,
+			alert:    alert,
+		}
+	}
+}
+
+type liveness struct {
+	deadline time.Duration
+	action   HungAction
+	alert    func(*Alert)
+}
+
+// runLive runs the runner executed by goroutine gid under liveness checking
+func (g *Group) runLive(runner Runner, gid uint64) error {
+	for {
+		hung, err := g.runLiveOnce(runner, gid)
+		if !hung || g.liveness.action == ReportHung {
+			return err
+		}
+		if g.liveness.action == CancelHung || g.ctx.Err() != nil {
+			return ErrHung
+		}
+		if g.logFunc != nil {
+			g.logFunc(&LogInfo{
+				Runner: runner,
+				Event:  Restart,
+			})
+		}
+	}
+}
+
+// runLiveOnce runs the runner once and returns if it has hung
+func (g *Group) runLiveOnce(runner Runner, gid uint64) (hung bool, err error) {
+	l := g.liveness
+	ctx, cancel := context.WithCancel(g.ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		start    = g.clock.Now()
+		lastBeat = start
+		stopped  bool
+	)
+	timer := g.clock.AfterFunc(l.deadline, func() {
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			return
+		}
+		hung = true
+		stopped = l.action != ReportHung
+		sinceBeat := g.clock.Now().Sub(lastBeat)
+		mu.Unlock()
+
+		if l.alert != nil {
+			l.alert(&Alert{
+				Runner:         runner,
+				Reason:         Hung,
+				Elapsed:        g.clock.Now().Sub(start),
+				SinceHeartbeat: sinceBeat,
+				Stack:          goroutine.Stack(gid),
+			})
+		}
+		if l.action != ReportHung {
+			cancel()
+		}
+	})
+	defer timer.Stop()
+
+	err = g.runOnce(run.WithHeartbeat(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return
+		}
+		lastBeat = g.clock.Now()
+		timer.Reset(l.deadline)
+	}), runner)
+
+	mu.Lock()
+	defer mu.Unlock()
+	stopped = true // no more alerts after exit
+	return hung, err
+}
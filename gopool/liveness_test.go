@@ -0,0 +1,152 @@
+package gopool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestLivenessHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Second
+	clock := runtest.NewFakeClock(time.Now())
+	alertChan := make(chan *Alert, 1)
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, Liveness(deadline, CancelHung, func(alert *Alert) {
+		alertChan <- alert
+	}))
+	beatChan := make(chan struct{})
+	if err := group.Go(Func(func(ctx context.Context) error {
+		for range beatChan {
+			run.Heartbeat(ctx)
+			beatChan <- struct{}{}
+		}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	// each heartbeat just before the deadline postpones it
+	clock.BlockUntil(1)
+	for i := 0; i < 10; i++ {
+		clock.Advance(deadline - time.Nanosecond)
+		beatChan <- struct{}{}
+		<-beatChan
+	}
+	close(beatChan)
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case alert := <-alertChan:
+		t.Fatalf("expect no alert but got %v", alert)
+	default:
+	}
+}
+
+func TestLivenessCancel(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Second
+	clock := runtest.NewFakeClock(time.Now())
+	alertChan := make(chan *Alert, 1)
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, Liveness(deadline, CancelHung, func(alert *Alert) {
+		alertChan <- alert
+	}))
+	if err := group.Go(Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(deadline)
+	if err := group.Wait(); err != ErrHung {
+		t.Fatalf("expect error %v got %v", ErrHung, err)
+	}
+	alert := <-alertChan
+	if alert.Reason != Hung {
+		t.Fatalf("expect reason %v got %v", Hung, alert.Reason)
+	}
+	if alert.SinceHeartbeat != deadline {
+		t.Fatalf("expect time since heartbeat %v but got %v", deadline, alert.SinceHeartbeat)
+	}
+}
+
+func TestLivenessRestart(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Second
+	clock := runtest.NewFakeClock(time.Now())
+	var events []Event
+	alertChan := make(chan *Alert, 1)
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx,
+		Liveness(deadline, RestartHung, func(alert *Alert) {
+			alertChan <- alert
+		}),
+		Log(func(info *LogInfo) {
+			events = append(events, info.Event)
+		}),
+	)
+	cnt := 0
+	if err := group.Go(Func(func(ctx context.Context) error {
+		cnt++
+		if cnt == 1 {
+			<-ctx.Done() // hang for the first time
+			return ctx.Err()
+		}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(deadline)
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 2 {
+		t.Fatalf("expect run exactly 2 times but got %d", cnt)
+	}
+	<-alertChan
+	wantEvents := []Event{Start, Restart, Exit}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("expect events %v got %v", wantEvents, events)
+	}
+	for i := range events {
+		if events[i] != wantEvents[i] {
+			t.Fatalf("expect events %v got %v", wantEvents, events)
+		}
+	}
+}
+
+func TestLivenessReport(t *testing.T) {
+	t.Parallel()
+
+	deadline := time.Second
+	clock := runtest.NewFakeClock(time.Now())
+	alertChan := make(chan *Alert, 1)
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, Liveness(deadline, ReportHung, func(alert *Alert) {
+		alertChan <- alert
+	}))
+	if err := group.Go(Func(func(ctx context.Context) error {
+		<-alertChan // not cancelled after reported
+		if ctx.Err() != nil {
+			t.Error("expect the hung runner is not cancelled")
+		}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(deadline)
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,10 @@
 package gopool
 
 import (
+	"context"
 	"fmt"
-	"reflect"
-	"runtime"
+
+	"h12.io/run"
 )
 
 // LogInfo is a logging event of a runner
@@ -11,6 +12,7 @@ type LogInfo struct {
 	Runner Runner
 	Event  Event
 	Err    error
+	Line   string // a line of output for the Output event
 }
 
 // Event enum of a runner
@@ -18,8 +20,15 @@ type Event int
 
 // Event constants
 const (
-	Start Event = iota // runner starts
-	Exit               // runner exits
+	Start   Event = iota // runner starts
+	Exit                 // runner exits
+	Restart              // runner restarts
+	Skip                 // runner skips scheduled runs, or is a duplicate
+	Output               // runner outputs a line, e.g. a subprocess
+
+	CircuitOpen     // circuit breaker opens
+	CircuitHalfOpen // circuit breaker becomes half-open
+	CircuitClose    // circuit breaker closes
 )
 
 // String representation of int enum
@@ -29,6 +38,18 @@ func (e Event) String() string {
 		return "start"
 	case Exit:
 		return "exit"
+	case Restart:
+		return "restart"
+	case Skip:
+		return "skip"
+	case Output:
+		return "output"
+	case CircuitOpen:
+		return "circuit open"
+	case CircuitHalfOpen:
+		return "circuit half-open"
+	case CircuitClose:
+		return "circuit close"
 	}
 	return ""
 }
@@ -44,33 +65,45 @@ func (li *LogInfo) String() string {
 	if li.Err != nil {
 		errMsg = ", err=" + li.Err.Error()
 	}
-	return fmt.Sprintf("%s %vs", li.RunnerName(), li.Event) + errMsg
+	msg := fmt.Sprintf("%s %vs", li.RunnerName(), li.Event) + errMsg
+	if li.Line != "" {
+		msg += ": " + li.Line
+	}
+	return msg
 }
 
-type namer interface {
-	Name() string
+// LogOutput logs a line of output of runner with the Output event, by the log
+// function of the group running with ctx. It returns false if there is no
+// such log function.
+func LogOutput(ctx context.Context, runner Runner, line string) bool {
+	logFunc := logFuncFromContext(ctx)
+	if logFunc == nil {
+		return false
+	}
+	logFunc(&LogInfo{
+		Runner: runner,
+		Event:  Output,
+		Line:   line,
+	})
+	return true
+}
+
+type logFuncKey struct{}
+
+// withLogFunc returns a copy of ctx carrying the log function of a group
+func withLogFunc(ctx context.Context, logFunc func(info *LogInfo)) context.Context {
+	return context.WithValue(ctx, logFuncKey{}, logFunc)
 }
 
-// logName tried to get a meaningful name of a variable for logging purpose,
-// meant to be used for logging the name of a runner.
-//
-// If it provides a Name() string method, it is returned.
-// If it is a function (e.g. run.RunnerFunc), the full name of the function is
-// returned.
-// Otherwise, the full name of the concrete type is returned.
+// logFuncFromContext returns the log function of the group running with ctx,
+// or nil if there is none
+func logFuncFromContext(ctx context.Context) func(info *LogInfo) {
+	logFunc, _ := ctx.Value(logFuncKey{}).(func(info *LogInfo))
+	return logFunc
+}
+
+// logName returns a meaningful name of a variable for logging purpose,
+// see run.Name
 func logName(runner interface{}) string {
-	if runner == nil {
-		return "nil"
-	}
-	if n, ok := runner.(namer); ok {
-		return n.Name()
-	}
-	typ := reflect.TypeOf(runner)
-	for typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-	}
-	if typ.Kind() == reflect.Func {
-		return runtime.FuncForPC(reflect.ValueOf(runner).Pointer()).Name()
-	}
-	return typ.PkgPath() + "." + typ.Name()
+	return run.Name(runner)
 }
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"h12.io/run"
+	"h12.io/run/internal/goroutine"
 )
 
 // ErrDispatchTimeout is returned when the context is cancelled when waiting for
@@ -23,10 +26,19 @@ func (dummyPool) Go(ctx context.Context, fn func()) error {
 // GoroutinePool provides a goroutine pool, see the documentation for method Go
 // for more information
 type GoroutinePool struct {
-	fnChan  chan func()
-	idle    time.Duration
-	maxChan chan struct{}
+	fnChan    chan func()
+	idle      time.Duration
+	max       *budget
+	sem       *semaphore
+	bulkhead  *bulkhead
+	clock     run.Clock
+	limiter   *limiter
+	parent    *GoroutinePool
+	budgets   []*budget // the goroutine budgets of the pool and its ancestors
+	ancestors []*budget // the goroutine budgets of the ancestors
 
+	mu        sync.Mutex
+	children  map[*GoroutinePool]struct{}
 	closeOnce sync.Once
 	quitChan  chan struct{}
 	wg        sync.WaitGroup
@@ -51,7 +63,47 @@ func IdleTime(idleTime time.Duration) PoolOption {
 // GoroutinePool can hold, if not specified, there is no upper limit
 func Max(n int) PoolOption {
 	return func(p *GoroutinePool) {
-		p.maxChan = make(chan struct{}, n)
+		p.max = newBudget(n)
+	}
+}
+
+// Capacity returns the option to specify the maximum sum of the weights of the
+// tasks that a GoroutinePool can run at the same time, see GoWeighted. If not
+// specified, there is no limit on the weights.
+func Capacity(n int64) PoolOption {
+	if n <= 0 {
+		panic("capacity should always be positive")
+	}
+	return func(p *GoroutinePool) {
+		p.sem = newSemaphore(n)
+	}
+}
+
+// Clock returns the option to specify the clock measuring the idle time and
+// the rate limit, if not specified, run.SystemClock is used. Unlike a Group,
+// which lives within the context it is created with, a pool outlives the
+// contexts passed to its Go method, and its idle timers belong to no single
+// call, so the clock is specified as an option rather than taken from
+// run.ClockFromContext.
+func Clock(clock run.Clock) PoolOption {
+	return func(p *GoroutinePool) {
+		p.clock = clock
+	}
+}
+
+// Parent returns the option to create a GoroutinePool as a child of parent.
+// The goroutines of the child, including the idle ones, count against the Max
+// of parent and its ancestors as well as the Max of the child, so a process
+// wide budget of goroutines can be divided into the limits of subsystems.
+// When a dispatching is blocked on the Max of an ancestor, the idle
+// goroutines of its descendants exit rather than wait for the idle time, so
+// that an idle child does not block its siblings.
+// Other options of parent do not apply to the child. Closing the child does
+// not affect parent or its other children, while closing parent closes the
+// child as well.
+func Parent(parent *GoroutinePool) PoolOption {
+	return func(p *GoroutinePool) {
+		p.parent = parent
 	}
 }
 
@@ -61,10 +113,24 @@ func NewGoroutinePool(options ...PoolOption) *GoroutinePool {
 		fnChan:   make(chan func()),
 		quitChan: make(chan struct{}),
 		idle:     time.Second,
+		clock:    run.SystemClock,
 	}
 	for _, opt := range options {
 		opt(p)
 	}
+	if p.bulkhead != nil && p.max != nil && p.bulkhead.size > cap(p.max.slots) {
+		panic("quotas size should not exceed max")
+	}
+	if p.max != nil {
+		p.budgets = append(p.budgets, p.max)
+	}
+	if p.parent != nil {
+		p.ancestors = p.parent.budgets
+		p.budgets = append(p.budgets, p.ancestors...)
+		if !p.parent.addChild(p) {
+			p.Close()
+		}
+	}
 	return p
 }
 
@@ -72,7 +138,12 @@ func NewGoroutinePool(options ...PoolOption) *GoroutinePool {
 // It returns nil if fn is successfully dispatched.
 // It returns ErrClosed if the pool is already closed.
 // It returns ErrDispatchTimeout if the context is cancelled when waiting for an
-// idle goroutine to be available.
+// idle goroutine or a token of the rate limit to be available.
+// It returns ErrRateLimited if the rate limit fails fast.
+//
+// If Capacity option is specified, fn is a task of weight 1, see GoWeighted.
+// If Quotas option is specified, fn takes a slot of the tenant of ctx, and Go
+// blocks and waits for the slot to be available.
 //
 // A gouroutine will stay idle and be reused for a period specified by IdleTime
 // option (default 1s).
@@ -81,74 +152,280 @@ func NewGoroutinePool(options ...PoolOption) *GoroutinePool {
 // that the pool can hold in total, and Go will block and wait for an idle
 // goroutine is available. Otherwise, there is no limit on the goroutine number.
 func (p *GoroutinePool) Go(ctx context.Context, fn func()) error {
+	return p.GoWeighted(ctx, 1, fn)
+}
+
+// GoWeighted is the same as Go, except that fn is a task of the given weight,
+// e.g. the memory it may take. If Capacity option is specified, the tasks are
+// admitted in the order of submission until the sum of the weights of the
+// running tasks reaches the capacity, and GoWeighted blocks in the meantime,
+// so a heavy task is not starved by the light ones submitted after it. It
+// returns ErrDispatchTimeout if the context is cancelled when waiting, and
+// ErrWeightTooLarge if weight exceeds the capacity. If Capacity option is not
+// specified, weight is ignored.
+//
+// If PoolRateLimit option is also specified, the token is taken after the
+// task is admitted by the capacity and the quotas, so that a task failing to
+// be admitted does not spend a token.
+func (p *GoroutinePool) GoWeighted(ctx context.Context, weight int64, fn func()) error {
+	if weight < 0 {
+		panic("weight should never be negative")
+	}
 	select {
 	case <-p.quitChan:
 		return ErrClosed
 	default:
 	}
 
-	for {
-		select {
-		case p.fnChan <- fn:
-			return nil
-		default:
-			if err := p.startGoroutine(ctx, fn); err != nil {
-				return err
-			}
-			select {
-			case p.fnChan <- fn:
-				return nil
-			case <-time.After(time.Millisecond):
-			}
+	if p.sem == nil && p.bulkhead == nil {
+		if err := p.wait(ctx); err != nil {
+			return err
+		}
+		return p.dispatch(ctx, fn)
+	}
+	release, err := p.admit(ctx, weight)
+	if err != nil {
+		return err
+	}
+	if err := p.wait(ctx); err != nil {
+		release()
+		return err
+	}
+	task := fn
+	fn = func() {
+		defer release()
+		task()
+	}
+	if err := p.dispatch(ctx, fn); err != nil {
+		release()
+		return err
+	}
+	return nil
+}
+
+// wait waits for a token of the rate limit if any
+func (p *GoroutinePool) wait(ctx context.Context) error {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.wait(ctx, p.clock, "")
+}
+
+// admit acquires a slot of the tenant of ctx and the weight for a task, and
+// returns the function releasing them
+func (p *GoroutinePool) admit(ctx context.Context, weight int64) (func(), error) {
+	release := func() {}
+	if p.bulkhead != nil {
+		tenant := TenantFromContext(ctx)
+		if err := p.bulkhead.acquire(ctx, p.quitChan, tenant); err != nil {
+			return nil, err
 		}
+		release = func() { p.bulkhead.release(tenant) }
 	}
+	if p.sem != nil {
+		if err := p.sem.acquire(ctx, p.quitChan, weight); err != nil {
+			release()
+			return nil, err
+		}
+		releaseSlot := release
+		release = func() {
+			p.sem.release(weight)
+			releaseSlot()
+		}
+	}
+	return release, nil
 }
 
-func (p *GoroutinePool) startGoroutine(ctx context.Context, fn func()) error {
-	if p.maxChan != nil {
-		select {
-		case p.maxChan <- struct{}{}:
-		case <-ctx.Done():
-			return ErrDispatchTimeout
+// dispatch dispatches fn onto an idle or a new goroutine
+func (p *GoroutinePool) dispatch(ctx context.Context, fn func()) error {
+	// prefer an idle goroutine
+	select {
+	case p.fnChan <- fn:
+		return nil
+	default:
+	}
+
+	// otherwise take the budgets of the pool and its ancestors in turn
+	for i, b := range p.budgets {
+		if b.tryTake() {
+			continue
 		}
+		handed, err := p.waitBudget(ctx, b, fn)
+		if handed || err != nil {
+			releaseBudgets(p.budgets[:i])
+			return err
+		}
+	}
+	p.startGoroutine(fn)
+	return nil
+}
+
+// waitBudget waits for a slot of b, while the idle goroutines holding b are
+// asked to exit. It returns true if fn is handed to an idle goroutine of the
+// pool instead.
+func (p *GoroutinePool) waitBudget(ctx context.Context, b *budget, fn func()) (bool, error) {
+	b.block()
+	defer b.unblock()
+	select {
+	case p.fnChan <- fn:
+		return true, nil
+	case b.slots <- struct{}{}:
+		return false, nil
+	case <-ctx.Done():
+		return false, ErrDispatchTimeout
+	case <-p.quitChan:
+		return false, ErrClosed
+	}
+}
+
+func releaseBudgets(budgets []*budget) {
+	for _, b := range budgets {
+		b.release()
 	}
+}
+
+// startGoroutine starts a new goroutine with fn as its first task
+func (p *GoroutinePool) startGoroutine(fn func()) {
 	p.wg.Add(1)
-	started := &sync.WaitGroup{}
-	started.Add(1)
 	go func() {
 		defer p.wg.Done()
-		if p.maxChan != nil {
-			defer func() {
-				<-p.maxChan
-			}()
+		defer releaseBudgets(p.budgets)
+		var gid uint64
+		var idle run.Timer
+		var exit chan struct{}
+		if len(p.ancestors) > 0 {
+			exit = make(chan struct{}, 1)
 		}
-		started.Done()
 		for {
-			select {
-			case fn := <-p.fnChan:
+			if trackingRunners() {
+				if gid == 0 {
+					gid = goroutine.ID()
+				}
+				runTracked(gid, fn)
+			} else {
 				fn()
-			case <-time.After(p.idle):
-				return
+			}
+
+			if !p.enterIdle(exit) {
+				// release the budgets for the blocked dispatching, unless
+				// it is of the pool and takes the goroutine directly
+				select {
+				case fn = <-p.fnChan:
+					continue
+				default:
+					return
+				}
+			}
+			if idle == nil {
+				idle = p.clock.NewTimer(p.idle)
+				defer idle.Stop()
+			} else {
+				if !idle.Stop() {
+					select {
+					case <-idle.C():
+					default:
+					}
+				}
+				idle.Reset(p.idle)
+			}
+			exiting := false
+			select {
+			case fn = <-p.fnChan:
+			case <-idle.C():
+				exiting = true
 			case <-p.quitChan:
+				exiting = true
+			case <-exit:
+				exiting = true
+			}
+			p.leaveIdle(exit)
+			if exiting {
 				return
 			}
 		}
 	}()
-	started.Wait()
-	return nil
 }
 
-// Close stops the pool from accepting new tasks, waits for existing tasks
-// complete and return nil. All subsequent calls will return ErrClosed
+// enterIdle registers an idle goroutine with its exit signal in the budgets of
+// the ancestors, it returns false if the goroutine should exit instead to
+// release the budgets for a blocked dispatching
+func (p *GoroutinePool) enterIdle(exit chan struct{}) bool {
+	select {
+	case <-exit:
+		return false
+	default:
+	}
+	for i, b := range p.ancestors {
+		if !b.enterIdle(exit) {
+			for _, b := range p.ancestors[:i] {
+				b.leaveIdle(exit)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// leaveIdle unregisters a goroutine no longer idle from the budgets of the
+// ancestors
+func (p *GoroutinePool) leaveIdle(exit chan struct{}) {
+	for _, b := range p.ancestors {
+		b.leaveIdle(exit)
+	}
+}
+
+// runTracked runs fn registered as being executed by goroutine gid
+func runTracked(gid uint64, fn func()) {
+	e := registry.enter(gid, fn, "", nil)
+	defer registry.exit(e)
+	fn()
+}
+
+// Close stops the pool and its children from accepting new tasks, waits for
+// existing tasks complete and return nil. All subsequent calls will return
+// ErrClosed
 func (p *GoroutinePool) Close() error {
 	first := false
 	p.closeOnce.Do(func() {
 		first = true
+		p.mu.Lock()
 		close(p.quitChan)
+		children := p.children
+		p.children = nil
+		p.mu.Unlock()
+		for child := range children {
+			child.Close()
+		}
 		p.wg.Wait()
+		if p.parent != nil {
+			p.parent.removeChild(p)
+		}
 	})
 	if !first {
 		return ErrClosed
 	}
 	return nil
 }
+
+// addChild adds a child to be closed along with the pool, it returns false if
+// the pool is already closed
+func (p *GoroutinePool) addChild(child *GoroutinePool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.quitChan:
+		return false
+	default:
+	}
+	if p.children == nil {
+		p.children = make(map[*GoroutinePool]struct{})
+	}
+	p.children[child] = struct{}{}
+	return true
+}
+
+func (p *GoroutinePool) removeChild(child *GoroutinePool) {
+	p.mu.Lock()
+	delete(p.children, child)
+	p.mu.Unlock()
+}
@@ -6,6 +6,9 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"h12.io/run/internal/goroutine"
+	"h12.io/run/runtest"
 )
 
 func newTestPoolSize(t *testing.T, n int) *GoroutinePool {
@@ -77,6 +80,7 @@ func TestPoolGoConcurrently(t *testing.T) {
 }
 
 func TestPoolNumGoroutines(t *testing.T) {
+	baseline := runtest.NewBaseline()
 	numBefore := runtime.NumGoroutine()
 
 	n := 10
@@ -91,10 +95,7 @@ func TestPoolNumGoroutines(t *testing.T) {
 	if err := pool.Close(); err != nil {
 		t.Fatal(err)
 	}
-	numAfter := runtime.NumGoroutine()
-	if numAfter != numBefore {
-		t.Fatalf("expect goroutine number remains the same after pool closed, but got %d != %d", numAfter, numBefore)
-	}
+	baseline.Verify(t)
 }
 
 func TestPoolGoroutineReuse(t *testing.T) {
@@ -114,7 +115,7 @@ func TestPoolGoroutineReuse(t *testing.T) {
 			pool.Go(context.Background(), func() {
 				defer wg.Done()
 				mu.Lock()
-				gidSet[getGID()] = true
+				gidSet[goroutine.ID()] = true
 				mu.Unlock()
 				<-quitChan
 			})
@@ -132,7 +133,7 @@ func TestPoolGoroutineReuse(t *testing.T) {
 			pool.Go(context.Background(), func() {
 				defer wg.Done()
 				mu.Lock()
-				gids = append(gids, getGID())
+				gids = append(gids, goroutine.ID())
 				mu.Unlock()
 			})
 		}
@@ -203,11 +204,13 @@ func TestPoolGoAfterClosed(t *testing.T) {
 }
 
 func TestPoolIdleTime(t *testing.T) {
-	idle := time.Millisecond
+	baseline := runtest.NewBaseline()
 	numBefore := runtime.NumGoroutine()
 
+	idle := time.Second
+	clock := runtest.NewFakeClock(time.Now())
 	n := 10
-	pool := NewGoroutinePool(IdleTime(idle), Max(n))
+	pool := NewGoroutinePool(IdleTime(idle), Max(n), Clock(clock))
 	defer pool.Close()
 	warmup(t, pool, n)
 
@@ -216,13 +219,15 @@ func TestPoolIdleTime(t *testing.T) {
 		t.Fatalf("goroutines used: expect %d but got %d", n, num)
 	}
 
-	// wait for time elapsed after idle time
-	time.Sleep(5 * idle)
-
-	numAfter := runtime.NumGoroutine()
-	if numAfter != numBefore {
-		t.Fatalf("expect goroutine number remains the same after idle time, but got %d != %d", numAfter, numBefore)
+	// all goroutines are idle
+	clock.BlockUntil(n)
+	clock.Advance(idle - time.Nanosecond)
+	if num := clock.Timers(); num != n {
+		t.Fatalf("expect %d goroutines still idle before idle time but got %d", n, num)
 	}
+
+	clock.Advance(time.Nanosecond)
+	baseline.Verify(t)
 }
 
 func TestInvalidIdleTime(t *testing.T) {
@@ -270,3 +275,130 @@ func TestPoolMaxSize(t *testing.T) {
 		t.Fatalf("expect dispatch timeout but got %v", err)
 	}
 }
+
+func TestPoolParentBudget(t *testing.T) {
+	t.Parallel()
+
+	parent := NewGoroutinePool(Max(2))
+	defer parent.Close()
+	a := NewGoroutinePool(Parent(parent), Max(2))
+	b := NewGoroutinePool(Parent(parent), Max(2))
+
+	quitChan := make(chan struct{})
+	defer close(quitChan)
+	if err := a.Go(context.Background(), func() { <-quitChan }); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Go(context.Background(), func() { <-quitChan }); err != nil {
+		t.Fatal(err)
+	}
+
+	// a child has room under its own Max but the parent budget is used up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect dispatch timeout but got %v", err)
+	}
+	if err := parent.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect dispatch timeout but got %v", err)
+	}
+}
+
+func TestPoolChildMax(t *testing.T) {
+	t.Parallel()
+
+	parent := NewGoroutinePool()
+	defer parent.Close()
+	child := NewGoroutinePool(Parent(parent), Max(1))
+
+	quitChan := make(chan struct{})
+	defer close(quitChan)
+	if err := child.Go(context.Background(), func() { <-quitChan }); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := child.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect dispatch timeout but got %v", err)
+	}
+	if err := parent.Go(ctx, func() {}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolChildIdle(t *testing.T) {
+	t.Parallel()
+
+	parent := NewGoroutinePool(Max(1))
+	defer parent.Close()
+	a := NewGoroutinePool(Parent(parent), IdleTime(time.Hour))
+	b := NewGoroutinePool(Parent(parent), IdleTime(time.Hour))
+
+	// the idle goroutine of a exits for b instead of holding the budget
+	done := make(chan struct{})
+	if err := a.Go(context.Background(), func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done = make(chan struct{})
+	if err := b.Go(ctx, func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	// and so does the idle goroutine of b for the parent
+	done = make(chan struct{})
+	if err := parent.Go(ctx, func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestPoolCloseChild(t *testing.T) {
+	t.Parallel()
+
+	parent := NewGoroutinePool(Max(1))
+	defer parent.Close()
+	a := NewGoroutinePool(Parent(parent))
+	b := NewGoroutinePool(Parent(parent))
+
+	// closing a child releases its goroutines to the siblings
+	if err := a.Go(context.Background(), func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Go(context.Background(), func() {}); err != ErrClosed {
+		t.Fatalf("expect %v but got %v", ErrClosed, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	if err := b.Go(ctx, func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestPoolCloseParent(t *testing.T) {
+	t.Parallel()
+
+	parent := NewGoroutinePool()
+	child := NewGoroutinePool(Parent(parent))
+	grandchild := NewGoroutinePool(Parent(child))
+	if err := parent.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*GoroutinePool{child, grandchild} {
+		if err := p.Go(context.Background(), func() {}); err != ErrClosed {
+			t.Fatalf("expect %v but got %v", ErrClosed, err)
+		}
+	}
+	late := NewGoroutinePool(Parent(parent))
+	if err := late.Go(context.Background(), func() {}); err != ErrClosed {
+		t.Fatalf("expect %v but got %v", ErrClosed, err)
+	}
+}
@@ -0,0 +1,162 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"h12.io/run"
+)
+
+// ErrRateLimited is returned when no token is available for a task and the
+// rate limit fails fast
+var ErrRateLimited = errors.New("failed to dispatch the goroutine due to rate limit")
+
+// Limit specifies a token bucket rate limit
+type Limit struct {
+	Rate     float64 // tokens added to the bucket per second
+	Burst    int     // maximum number of tokens in the bucket
+	PerName  bool    // keeps a separate bucket for each runner name, groups only
+	FailFast bool    // returns ErrRateLimited instead of waiting for a token
+}
+
+// RateLimit specifies the rate limit of a group, each call to Go takes a
+// token from the bucket before dispatching the runner. The groups created with
+// the same option share the same buckets, so that the rate of many
+// short-lived groups can be limited as a whole.
+func RateLimit(limit Limit) GroupOption {
+	l := newLimiter(limit)
+	return func(g *Group) {
+		g.limiter = l
+	}
+}
+
+// PoolRateLimit returns the option to specify the rate limit of a
+// GoroutinePool, each call to Go takes a token from the bucket before
+// dispatching the function. PerName is not supported, because the functions
+// submitted by a Group are all the same closure, use RateLimit of the groups
+// instead.
+func PoolRateLimit(limit Limit) PoolOption {
+	if limit.PerName {
+		panic("PerName is not supported by a pool")
+	}
+	l := newLimiter(limit)
+	return func(p *GoroutinePool) {
+		p.limiter = l
+	}
+}
+
+type limiter struct {
+	limit Limit
+
+	mu        sync.Mutex
+	bucket    *bucket
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(limit Limit) *limiter {
+	if limit.Rate <= 0 {
+		panic("rate should always be positive")
+	}
+	if limit.Burst <= 0 {
+		panic("burst should always be positive")
+	}
+	return &limiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// wait takes a token from the bucket of name, it waits for the token to be
+// available unless FailFast is set, and returns ErrDispatchTimeout if the
+// context is cancelled while waiting
+func (l *limiter) wait(ctx context.Context, clock run.Clock, name string) error {
+	l.mu.Lock()
+	b := l.getBucket(name, clock)
+	now := clock.Now()
+	b.tokens += now.Sub(b.last).Seconds() * l.limit.Rate
+	if burst := float64(l.limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	if l.limit.FailFast {
+		l.mu.Unlock()
+		return ErrRateLimited
+	}
+	// reserve the token in advance, so that subsequent calls wait in order
+	b.tokens--
+	delay := time.Duration(-b.tokens / l.limit.Rate * float64(time.Second))
+	l.mu.Unlock()
+
+	timer := clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.tokens++ // cancel the reservation
+		l.mu.Unlock()
+		return ErrDispatchTimeout
+	}
+}
+
+func (l *limiter) getBucket(name string, clock run.Clock) *bucket {
+	if !l.limit.PerName {
+		if l.bucket == nil {
+			l.bucket = l.newBucket(clock)
+		}
+		return l.bucket
+	}
+	now := clock.Now()
+	if now.Sub(l.lastSweep) >= l.fillTime() {
+		l.sweep(now)
+		l.lastSweep = now
+	}
+	b, ok := l.buckets[name]
+	if !ok {
+		b = l.newBucket(clock)
+		l.buckets[name] = b
+	}
+	return b
+}
+
+// sweep evicts the buckets that are full by now, which are no different from
+// new ones, so that the buckets of the names no longer seen do not pile up
+func (l *limiter) sweep(now time.Time) {
+	burst := float64(l.limit.Burst)
+	for name, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate >= burst {
+			delete(l.buckets, name)
+		}
+	}
+}
+
+// fillTime returns the time for an empty bucket to be full
+func (l *limiter) fillTime() time.Duration {
+	return time.Duration(float64(l.limit.Burst) / l.limit.Rate * float64(time.Second))
+}
+
+func (l *limiter) newBucket(clock run.Clock) *bucket {
+	return &bucket{tokens: float64(l.limit.Burst), last: clock.Now()}
+}
+
+// name returns the bucket name of a runner or function
+func (l *limiter) name(v interface{}) string {
+	if !l.limit.PerName {
+		return ""
+	}
+	return logName(v)
+}
@@ -0,0 +1,160 @@
+package gopool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestGroupRateLimit(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, RateLimit(Limit{Rate: 1, Burst: 2}))
+	for i := 0; i < 2; i++ {
+		if err := group.Go(&runtest.FakeRunner{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- group.Go(&runtest.FakeRunner{})
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second - time.Nanosecond)
+	select {
+	case err := <-errChan:
+		t.Fatalf("expect waiting for a token but returned %v", err)
+	default:
+	}
+	clock.Advance(time.Nanosecond)
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroupRateLimitCancel(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, RateLimit(Limit{Rate: 1, Burst: 1}))
+	if err := group.Go(&runtest.FakeRunner{}); err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error)
+	go func() {
+		errChan <- group.Go(&runtest.FakeRunner{})
+	}()
+	clock.BlockUntil(1)
+	group.Cancel()
+	if err := <-errChan; err != ErrDispatchTimeout {
+		t.Fatalf("expect error %v got %v", ErrDispatchTimeout, err)
+	}
+}
+
+func TestGroupRateLimitFailFast(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, RateLimit(Limit{Rate: 1, Burst: 1, FailFast: true}))
+	if err := group.Go(&runtest.FakeRunner{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Go(&runtest.FakeRunner{}); err != ErrRateLimited {
+		t.Fatalf("expect error %v got %v", ErrRateLimited, err)
+	}
+	clock.Advance(time.Second)
+	if err := group.Go(&runtest.FakeRunner{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroupRateLimitPerName(t *testing.T) {
+	t.Parallel()
+
+	limit := RateLimit(Limit{Rate: 1, Burst: 1, PerName: true, FailFast: true})
+	group := NewGroup(context.Background(), limit)
+	for _, name := range []string{"a", "b"} {
+		if err := group.Go(namedRunner{name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := group.Go(namedRunner{name: "a"}); err != ErrRateLimited {
+		t.Fatalf("expect error %v got %v", ErrRateLimited, err)
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the buckets are shared by the groups created with the same option
+	group = NewGroup(context.Background(), limit)
+	if err := group.Go(namedRunner{name: "b"}); err != ErrRateLimited {
+		t.Fatalf("expect error %v got %v", ErrRateLimited, err)
+	}
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(PoolRateLimit(Limit{Rate: 1, Burst: 1, FailFast: true}))
+	defer pool.Close()
+	if err := pool.Go(context.Background(), func() {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Go(context.Background(), func() {}); err != ErrRateLimited {
+		t.Fatalf("expect error %v got %v", ErrRateLimited, err)
+	}
+}
+
+func TestGroupRateLimitEvict(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, RateLimit(Limit{Rate: 1, Burst: 2, PerName: true, FailFast: true}))
+	for i := 0; i < 100; i++ {
+		if err := group.Go(namedRunner{name: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(group.limiter.buckets); n != 100 {
+		t.Fatalf("expect 100 buckets but got %d", n)
+	}
+
+	// the buckets are full again after the fill time
+	clock.Advance(2 * time.Second)
+	if err := group.Go(namedRunner{name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(group.limiter.buckets); n != 1 {
+		t.Fatalf("expect the full buckets evicted but got %d buckets", n)
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolRateLimitPerName(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect panic")
+		}
+	}()
+	PoolRateLimit(Limit{Rate: 1, Burst: 1, PerName: true})
+}
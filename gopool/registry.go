@@ -0,0 +1,180 @@
+package gopool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"h12.io/run/internal/goroutine"
+)
+
+// State enum of a running runner
+type State int
+
+// State constants
+const (
+	Running   State = iota // runner is running
+	Cancelled              // runner's context is cancelled but it has not returned yet
+)
+
+// String representation of int enum
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Cancelled:
+		return "cancelled"
+	}
+	return ""
+}
+
+// MarshalText satisfies encoding.TextMarshaler
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText satisfies encoding.TextUnmarshaler
+func (s *State) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "running":
+		*s = Running
+	case "cancelled":
+		*s = Cancelled
+	default:
+		return fmt.Errorf("invalid runner state %q", text)
+	}
+	return nil
+}
+
+// RunnerInfo is a snapshot of a runner executing in a Group or a
+// GoroutinePool
+type RunnerInfo struct {
+	Name        string        `json:"name"`
+	Group       string        `json:"group,omitempty"`
+	Start       time.Time     `json:"start"`
+	Elapsed     time.Duration `json:"elapsed"`
+	State       State         `json:"state"`
+	GoroutineID uint64        `json:"goroutine"`
+	Stack       string        `json:"stack,omitempty"`
+}
+
+// Runners returns a snapshot of the runners currently executing in any Group
+// or GoroutinePool, the longest running first. The runners are only tracked
+// after TrackRunners(true) is called, e.g. by importing h12.io/run/gopool/debug.
+//
+// If stack is true, the stack of the goroutine executing each runner is
+// captured as well.
+func Runners(stack bool) []*RunnerInfo {
+	return registry.snapshot(stack)
+}
+
+// tracking is 1 if the runners are tracked by the registry
+var tracking int32
+
+// TrackRunners enables or disables tracking the runners executing in Groups
+// and GoroutinePools, see Runners. Tracking is disabled by default, because
+// it takes the goroutine ID and a global lock for each runner.
+func TrackRunners(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&tracking, v)
+}
+
+func trackingRunners() bool {
+	return atomic.LoadInt32(&tracking) == 1
+}
+
+// registry tracks the runners currently executing in all Groups and
+// GoroutinePools
+var registry = &runnerRegistry{entries: make(map[uint64]*entry)}
+
+// runnerRegistry indexes the running entries by goroutine ID. When a Group
+// runs on a GoroutinePool, the entry of the group runner is stacked upon the
+// entry of the pool task, and only the innermost one is visible.
+type runnerRegistry struct {
+	mu      sync.Mutex
+	entries map[uint64]*entry
+}
+
+type entry struct {
+	runner interface{}
+	group  string
+	ctx    context.Context
+	start  time.Time
+	gid    uint64
+	parent *entry
+}
+
+// enter registers runner as being executed by goroutine gid
+func (r *runnerRegistry) enter(gid uint64, runner interface{}, group string, ctx context.Context) *entry {
+	e := &entry{
+		runner: runner,
+		group:  group,
+		ctx:    ctx,
+		start:  time.Now(),
+		gid:    gid,
+	}
+	r.mu.Lock()
+	e.parent = r.entries[gid]
+	r.entries[gid] = e
+	r.mu.Unlock()
+	return e
+}
+
+// exit unregisters the entry returned by enter, if e is not nil
+func (r *runnerRegistry) exit(e *entry) {
+	if e == nil {
+		return
+	}
+	r.mu.Lock()
+	if e.parent != nil {
+		r.entries[e.gid] = e.parent
+	} else {
+		delete(r.entries, e.gid)
+	}
+	r.mu.Unlock()
+}
+
+func (r *runnerRegistry) snapshot(stack bool) []*RunnerInfo {
+	var stacks map[uint64][]byte
+	if stack {
+		stacks = goroutine.Stacks()
+	}
+	now := time.Now()
+	r.mu.Lock()
+	infos := make([]*RunnerInfo, 0, len(r.entries))
+	runners := make([]interface{}, 0, len(r.entries))
+	for _, e := range r.entries {
+		infos = append(infos, e.info(now))
+		runners = append(runners, e.runner)
+	}
+	r.mu.Unlock()
+	// names are resolved out of the lock because it is relatively expensive
+	for i, info := range infos {
+		info.Name = logName(runners[i])
+		info.Stack = string(stacks[info.GoroutineID])
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Start.Before(infos[j].Start)
+	})
+	return infos
+}
+
+func (e *entry) info(now time.Time) *RunnerInfo {
+	state := Running
+	if e.ctx != nil && e.ctx.Err() != nil {
+		state = Cancelled
+	}
+	return &RunnerInfo{
+		Group:       e.group,
+		Start:       e.start,
+		Elapsed:     now.Sub(e.start),
+		State:       state,
+		GoroutineID: e.gid,
+	}
+}
@@ -0,0 +1,97 @@
+package gopool
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func findRunner(name string, stack bool) *RunnerInfo {
+	for _, info := range Runners(stack) {
+		if info.Name == name {
+			return info
+		}
+	}
+	return nil
+}
+
+func TestRunnersGroup(t *testing.T) {
+	t.Parallel()
+	TrackRunners(true)
+
+	group := NewGroup(context.Background(), Name("test group"))
+	started := make(chan struct{})
+	quitChan := make(chan struct{})
+	if err := group.Go(namedBlockingRunner{name: "TestRunnersGroup", started: started, quit: quitChan}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	info := findRunner("TestRunnersGroup", true)
+	if info == nil {
+		t.Fatal("expect the runner is registered")
+	}
+	if info.Group != "test group" {
+		t.Fatalf("expect group %q got %q", "test group", info.Group)
+	}
+	if info.State != Running {
+		t.Fatalf("expect state %v got %v", Running, info.State)
+	}
+	if !strings.Contains(info.Stack, "namedBlockingRunner") {
+		t.Fatalf("expect the stack of the runner but got %s", info.Stack)
+	}
+
+	group.Cancel()
+	if info := findRunner("TestRunnersGroup", false); info == nil || info.State != Cancelled {
+		t.Fatalf("expect cancelled runner but got %v", info)
+	}
+
+	close(quitChan)
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if info := findRunner("TestRunnersGroup", false); info != nil {
+		t.Fatal("expect the runner is unregistered after exit")
+	}
+}
+
+func TestRunnersGroupOnPool(t *testing.T) {
+	t.Parallel()
+	TrackRunners(true)
+
+	pool := NewGoroutinePool()
+	defer pool.Close()
+	group := NewGroup(context.Background(), Pool(pool))
+	started := make(chan struct{})
+	quitChan := make(chan struct{})
+	if err := group.Go(namedBlockingRunner{name: "TestRunnersGroupOnPool", started: started, quit: quitChan}); err != nil {
+		t.Fatal(err)
+	}
+	defer group.Wait()
+	defer close(quitChan)
+	<-started
+
+	info := findRunner("TestRunnersGroupOnPool", false)
+	if info == nil {
+		t.Fatal("expect the runner is registered")
+	}
+	for _, other := range Runners(false) {
+		if other.Name != info.Name && other.GoroutineID == info.GoroutineID {
+			t.Fatalf("expect only the innermost runner is visible but got %s", other.Name)
+		}
+	}
+}
+
+type namedBlockingRunner struct {
+	name    string
+	started chan struct{}
+	quit    chan struct{}
+}
+
+func (r namedBlockingRunner) Name() string { return r.name }
+
+func (r namedBlockingRunner) Run(ctx context.Context) error {
+	close(r.started)
+	<-r.quit
+	return nil
+}
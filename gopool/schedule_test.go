@@ -0,0 +1,60 @@
+package gopool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2021, 4, 16, 10, 30, 15, 0, time.UTC) // Friday
+	testcases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2021, 4, 16, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 4, 16, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2021, 4, 16, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 4, 16, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, 4, 17, 0, 0, 0, 0, time.UTC)},
+		{"30 9-17/4 * * *", time.Date(2021, 4, 16, 13, 30, 0, 0, time.UTC)},
+		{"0 0 * * mon", time.Date(2021, 4, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2021, 4, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * fri", time.Date(2021, 4, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+			s, err := ParseSchedule(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Next(from); !got.Equal(tc.want) {
+				t.Fatalf("expect %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseScheduleError(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Fatalf("expect error for %q but got nil", expr)
+		}
+	}
+}
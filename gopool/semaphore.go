@@ -0,0 +1,105 @@
+package gopool
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrWeightTooLarge is returned when the weight of a task exceeds the
+// capacity of the pool, so that it can never be admitted
+var ErrWeightTooLarge = errors.New("the weight of the task exceeds the capacity of the pool")
+
+// semaphore is a weighted semaphore admitting the waiters in FIFO order, so
+// that a heavy waiter is not starved by the light ones arriving after it
+type semaphore struct {
+	size int64
+
+	mu      sync.Mutex
+	cur     int64
+	waiters list.List
+}
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newSemaphore(size int64) *semaphore {
+	return &semaphore{size: size}
+}
+
+// acquire acquires n from the semaphore, blocking until it is available. It
+// returns ErrDispatchTimeout if ctx is cancelled and ErrClosed if quit is
+// closed before that.
+func (s *semaphore) acquire(ctx context.Context, quit <-chan struct{}, n int64) error {
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrWeightTooLarge
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(semaphoreWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		err = ErrDispatchTimeout
+	case <-quit:
+		err = ErrClosed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-ready:
+		// acquired meanwhile, pretend the cancellation comes too late
+		return nil
+	default:
+	}
+	front := s.waiters.Front() == elem
+	s.waiters.Remove(elem)
+	if front {
+		// the waiters behind may fit now
+		s.notify()
+	}
+	return err
+}
+
+// release releases n to the semaphore and admits the waiters that fit
+func (s *semaphore) release(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	if s.cur < 0 {
+		s.mu.Unlock()
+		panic("semaphore released more than held")
+	}
+	s.notify()
+	s.mu.Unlock()
+}
+
+// notify admits the waiters in order until the first one that does not fit
+func (s *semaphore) notify() {
+	for {
+		elem := s.waiters.Front()
+		if elem == nil {
+			return
+		}
+		w := elem.Value.(semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(elem)
+		close(w.ready)
+	}
+}
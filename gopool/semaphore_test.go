@@ -0,0 +1,177 @@
+package gopool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h12.io/run/runtest"
+)
+
+func TestPoolGoWeightedCapacity(t *testing.T) {
+	t.Parallel()
+
+	const capacity = 10
+	pool := NewGoroutinePool(Capacity(capacity))
+	defer pool.Close()
+
+	var (
+		inflight int64
+		peak     int64
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 100; i++ {
+		weight := int64(i%4 + 1)
+		wg.Add(1)
+		if err := pool.GoWeighted(context.Background(), weight, func() {
+			defer wg.Done()
+			n := atomic.AddInt64(&inflight, weight)
+			defer atomic.AddInt64(&inflight, -weight)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			runtime.Gosched()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if peak > capacity {
+		t.Fatalf("expect the in-flight weights no more than %d but got %d", capacity, peak)
+	}
+}
+
+func TestPoolGoWeightedFIFO(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Capacity(4))
+	defer pool.Close()
+
+	release := make(chan struct{})
+	if err := pool.GoWeighted(context.Background(), 3, func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+
+	// the heavy task waits for the capacity
+	order := make(chan string, 2)
+	heavyDispatched := make(chan struct{})
+	heavyCtx := newWaitingContext(context.Background())
+	go func() {
+		defer close(heavyDispatched)
+		if err := pool.GoWeighted(heavyCtx, 4, func() { order <- "heavy" }); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-heavyCtx.waiting
+
+	// the light task fits in the capacity but should not jump the queue
+	lightDispatched := make(chan struct{})
+	lightCtx := newWaitingContext(context.Background())
+	go func() {
+		defer close(lightDispatched)
+		if err := pool.GoWeighted(lightCtx, 1, func() { order <- "light" }); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-lightCtx.waiting
+
+	close(release)
+	<-heavyDispatched
+	<-lightDispatched
+	if first, second := <-order, <-order; first != "heavy" || second != "light" {
+		t.Fatalf("expect heavy before light but got %s, %s", first, second)
+	}
+}
+
+func TestPoolGoWeightedErrors(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Capacity(2))
+	defer pool.Close()
+
+	if err := pool.GoWeighted(context.Background(), 3, func() {}); err != ErrWeightTooLarge {
+		t.Fatalf("expect %v but got %v", ErrWeightTooLarge, err)
+	}
+
+	release := make(chan struct{})
+	if err := pool.GoWeighted(context.Background(), 2, func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.Go(ctx, func() {}); err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+
+	// the cancelled waiter is removed, or it would take the capacity first
+	close(release)
+	done := make(chan struct{})
+	if err := pool.GoWeighted(context.Background(), 2, func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestSemaphoreCancelFront(t *testing.T) {
+	t.Parallel()
+
+	sem := newSemaphore(4)
+	if err := sem.acquire(context.Background(), nil, 3); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	heavyCtx := newWaitingContext(ctx)
+	heavy := make(chan error, 1)
+	go func() { heavy <- sem.acquire(heavyCtx, nil, 4) }()
+	<-heavyCtx.waiting
+	lightCtx := newWaitingContext(context.Background())
+	light := make(chan error, 1)
+	go func() { light <- sem.acquire(lightCtx, nil, 1) }()
+	<-lightCtx.waiting
+
+	// cancelling the heavy waiter at the front admits the light one behind
+	cancel()
+	if err := <-heavy; err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+	if err := <-light; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPoolGoWeightedRateLimit(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	pool := NewGoroutinePool(Capacity(1), Clock(clock), PoolRateLimit(Limit{Rate: 1, Burst: 1, FailFast: true}))
+	defer pool.Close()
+
+	release := make(chan struct{})
+	if err := pool.Go(context.Background(), func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+
+	// the task failing to be admitted does not spend the token
+	ctx, cancel := context.WithCancel(context.Background())
+	waitingCtx := newWaitingContext(ctx)
+	errChan := make(chan error, 1)
+	go func() { errChan <- pool.Go(waitingCtx, func() {}) }()
+	<-waitingCtx.waiting
+	cancel()
+	if err := <-errChan; err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+	close(release)
+	done := make(chan struct{})
+	if err := pool.Go(context.Background(), func() { close(done) }); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
@@ -0,0 +1,130 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+
+	"h12.io/run/internal/ctxutil"
+)
+
+// SingleFlight de-duplicates the concurrent runs of runners with the same
+// key: while a run is in flight, the callers with the same key wait for and
+// share its result instead of running their own runners.
+type SingleFlight struct {
+	pool GroupPool
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	runner  Runner
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+	waiters int
+	failed  bool // failed to be dispatched, the callers joined retry
+}
+
+// NewSingleFlight creates a new SingleFlight running the runners on pool, if
+// pool is nil, new goroutines are started instead
+func NewSingleFlight(pool GroupPool) *SingleFlight {
+	if pool == nil {
+		pool = dummyPool{}
+	}
+	return &SingleFlight{
+		pool:    pool,
+		flights: make(map[string]*flight),
+	}
+}
+
+// Do runs runner on the pool unless a run with the same key is in flight, in
+// which case it joins that run. It waits for the run to exit and returns the
+// runner actually run, so that its result can be retrieved, along with its
+// error. A panic of the runner is recovered and returned as a PanicError to
+// every caller.
+//
+// The shared run has its own context carrying the values of ctx of the first
+// caller. When ctx of a caller is done, Do returns ctx.Err() without
+// cancelling the shared run, unless every caller has returned, in which case
+// the shared run is cancelled and the next caller starts a new run.
+//
+// If the run fails to be dispatched, the error is returned to the first
+// caller only, and the callers joined meanwhile try again with their own
+// runners and contexts.
+func (s *SingleFlight) Do(ctx context.Context, key string, runner Runner) (Runner, error) {
+	for {
+		f, err := s.join(ctx, key, runner)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-f.done:
+			if f.failed {
+				continue
+			}
+			return f.runner, f.err
+		case <-ctx.Done():
+		}
+		s.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if s.flights[key] == f {
+				delete(s.flights, key)
+			}
+		}
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// join joins the flight of key, or starts a new one running runner, it
+// returns the error of the dispatching
+func (s *SingleFlight) join(ctx context.Context, key string, runner Runner) (*flight, error) {
+	s.mu.Lock()
+	if f, ok := s.flights[key]; ok {
+		f.waiters++
+		s.mu.Unlock()
+		return f, nil
+	}
+	runCtx, cancel := context.WithCancel(ctxutil.Detach(ctx))
+	f := &flight{
+		runner:  runner,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		waiters: 1,
+	}
+	s.flights[key] = f
+	s.mu.Unlock()
+
+	if err := s.pool.Go(ctx, func() { s.run(runCtx, key, f) }); err != nil {
+		f.err = err
+		f.failed = true
+		s.finish(key, f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// run runs the runner of f and recovers its panic
+func (s *SingleFlight) run(ctx context.Context, key string, f *flight) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = NewPanicError(r)
+		}
+		s.finish(key, f)
+	}()
+	f.err = f.runner.Run(ctx)
+}
+
+// finish removes f and wakes up its callers
+func (s *SingleFlight) finish(key string, f *flight) {
+	s.mu.Lock()
+	if s.flights[key] == f {
+		delete(s.flights, key)
+	}
+	s.mu.Unlock()
+	f.cancel()
+	close(f.done)
+}
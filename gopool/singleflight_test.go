@@ -0,0 +1,251 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h12.io/run"
+)
+
+func TestSingleFlightShare(t *testing.T) {
+	t.Parallel()
+
+	const callers = 10
+	sf := NewSingleFlight(nil)
+	errFail := errors.New("fail")
+	var calls int64
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := run.Func(func(ctx context.Context) error {
+		atomic.AddInt64(&calls, 1)
+		close(started)
+		<-release
+		return errFail
+	})
+
+	var wg sync.WaitGroup
+	results := make(chan run.Runner, callers)
+	for i := 0; i < callers; i++ {
+		runner := first
+		if i > 0 {
+			runner = run.Func(func(ctx context.Context) error {
+				atomic.AddInt64(&calls, 1)
+				return nil
+			})
+		}
+		ctx := newWaitingContext(context.Background())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := sf.Do(ctx, "key", runner)
+			if err != errFail {
+				t.Errorf("expect %v but got %v", errFail, err)
+			}
+			results <- r
+		}()
+		if i == 0 {
+			<-started
+		} else {
+			<-ctx.waiting
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Fatalf("expect 1 run but got %d", n)
+	}
+	for r := range results {
+		if run.Name(r) != run.Name(first) {
+			t.Fatalf("expect the first runner to be shared but got %s", run.Name(r))
+		}
+	}
+
+	// the finished run is not shared with the next caller
+	if r, err := sf.Do(context.Background(), "key", run.Func(func(context.Context) error {
+		atomic.AddInt64(&calls, 1)
+		return nil
+	})); err != nil || run.Name(r) == run.Name(first) {
+		t.Fatalf("expect a new run but got %s, %v", run.Name(r), err)
+	}
+}
+
+func TestSingleFlightDetach(t *testing.T) {
+	t.Parallel()
+
+	sf := NewSingleFlight(nil)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runner := run.Func(func(ctx context.Context) error {
+		close(started)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	detached := make(chan error, 1)
+	go func() {
+		_, err := sf.Do(ctx, "key", runner)
+		detached <- err
+	}()
+	<-started
+	shared := make(chan error, 1)
+	sharedCtx := newWaitingContext(context.Background())
+	go func() {
+		_, err := sf.Do(sharedCtx, "key", nil)
+		shared <- err
+	}()
+	<-sharedCtx.waiting
+
+	cancel()
+	if err := <-detached; err != context.Canceled {
+		t.Fatalf("expect %v but got %v", context.Canceled, err)
+	}
+	close(release)
+	if err := <-shared; err != nil {
+		t.Fatalf("expect the shared run not cancelled but got %v", err)
+	}
+}
+
+func TestSingleFlightDetachAll(t *testing.T) {
+	t.Parallel()
+
+	sf := NewSingleFlight(nil)
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	runner := run.Func(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := sf.Do(ctx, "key", runner)
+		errChan <- err
+	}()
+	<-started
+	cancel()
+	if err := <-errChan; err != context.Canceled {
+		t.Fatalf("expect %v but got %v", context.Canceled, err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expect the shared run cancelled")
+	}
+
+	// a new call starts a new run
+	if _, err := sf.Do(context.Background(), "key", run.Func(func(context.Context) error {
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSingleFlightPanic(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Max(1))
+	defer pool.Close()
+	sf := NewSingleFlight(pool)
+	_, err := sf.Do(context.Background(), "key", run.Func(func(context.Context) error {
+		panic("boom")
+	}))
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Err != "boom" {
+		t.Fatalf("expect a PanicError but got %v", err)
+	}
+}
+
+func TestSingleFlightDispatchError(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Max(1))
+	defer pool.Close()
+	release := make(chan struct{})
+	if err := pool.Go(context.Background(), func() { <-release }); err != nil {
+		t.Fatal(err)
+	}
+
+	sf := NewSingleFlight(pool)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := sf.Do(ctx, "key", run.Func(func(context.Context) error { return nil }))
+	if err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+
+	// the failed run is not shared with the next caller
+	close(release)
+	if _, err := sf.Do(context.Background(), "key", run.Func(func(context.Context) error {
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSingleFlightDispatchRetry(t *testing.T) {
+	t.Parallel()
+
+	// the first dispatching stalls until its ctx is cancelled
+	pool := &stallingPool{stalled: make(chan struct{})}
+	sf := NewSingleFlight(pool)
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := sf.Do(ctx, "key", run.Func(func(context.Context) error { return nil }))
+		errChan <- err
+	}()
+	<-pool.stalled
+
+	joinedCtx := newWaitingContext(context.Background())
+	type result struct {
+		runner run.Runner
+		err    error
+	}
+	joined := make(chan result, 1)
+	runner := run.Func(func(context.Context) error { return nil })
+	go func() {
+		r, err := sf.Do(joinedCtx, "key", runner)
+		joined <- result{r, err}
+	}()
+	<-joinedCtx.waiting
+
+	cancel()
+	if err := <-errChan; err != ErrDispatchTimeout {
+		t.Fatalf("expect %v but got %v", ErrDispatchTimeout, err)
+	}
+	// the joined caller gets its own run instead of the dispatch error
+	if res := <-joined; res.err != nil || run.Name(res.runner) != run.Name(runner) {
+		t.Fatalf("expect the joined caller to retry but got %s, %v", run.Name(res.runner), res.err)
+	}
+}
+
+// waitingContext closes waiting when its Done is called, i.e. when Do waits
+// on it after joining a run
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func newWaitingContext(ctx context.Context) *waitingContext {
+	return &waitingContext{Context: ctx, waiting: make(chan struct{})}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.once.Do(func() { close(c.waiting) })
+	return c.Context.Done()
+}
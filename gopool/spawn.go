@@ -0,0 +1,144 @@
+package gopool
+
+// Dedup specifies the function returning the key of a runner, so that a
+// runner is skipped if one with the same key has been submitted to the group
+// before, e.g. a URL already crawled. The skipped runner is logged with the
+// Skip event. The key of a runner failing to be dispatched is forgotten, so
+// that it can be submitted again. The keys are kept for the lifetime of the
+// group, so the memory grows with the number of the distinct keys submitted.
+func Dedup(key func(runner Runner) string) GroupOption {
+	return func(g *Group) {
+		g.dedupKey = key
+	}
+}
+
+// Spawn submits the runner to the group without waiting for it to be
+// dispatched, so a runner spawning more runners, which can be found by
+// run.GroupFromContext from its context, never deadlocks on a busy pool.
+// Wait returns only after the spawned runners exit as well, i.e. when the
+// whole task graph is quiescent.
+//
+// The spawned runners are queued in the group, and dispatched in order by at
+// most one background goroutine of the group, or run by the goroutines of
+// the runners of the group when they exit, so the goroutines waiting for the
+// pool do not grow with the spawned runners, while the queue is unbounded.
+//
+// Spawn must be called by a running runner of the group, or before Wait is
+// called. It returns the error of the context of the group if the group is
+// cancelled, and nil without queueing if the runner is a duplicate, see
+// Dedup. If the dispatching fails in the background, the error cancels the
+// group like an error returned from a runner. The runners still queued when
+// the group is cancelled are dropped.
+func (g *Group) Spawn(runner Runner) error {
+	if err := g.ctx.Err(); err != nil {
+		return err
+	}
+	if g.duplicate(runner) {
+		return nil
+	}
+	g.wg.Add(1)
+	g.mu.Lock()
+	g.spawned = append(g.spawned, runner)
+	start := !g.dispatching
+	g.dispatching = true
+	g.mu.Unlock()
+	if start {
+		go g.dispatchSpawned()
+	}
+	return nil
+}
+
+// dispatchSpawned dispatches the spawned runners until the queue is empty
+func (g *Group) dispatchSpawned() {
+	for {
+		runner, ok := g.popSpawned(true)
+		if !ok {
+			return
+		}
+		if g.ctx.Err() == nil {
+			g.fail(g.start(runner))
+		}
+		g.wg.Done()
+	}
+}
+
+// runSpawned runs the spawned runners on the current goroutine, which is
+// already taken from the pool, until the queue is empty
+func (g *Group) runSpawned() {
+	for {
+		runner, ok := g.popSpawned(false)
+		if !ok {
+			return
+		}
+		if g.ctx.Err() == nil {
+			if m, err := g.admit(runner); err != nil {
+				g.forget(runner)
+				g.fail(err)
+			} else {
+				g.runMember(m, runner)
+			}
+		}
+		g.wg.Done()
+	}
+}
+
+// popSpawned removes the first spawned runner from the queue, it returns
+// false if the queue is empty, in which case the dispatching is done if
+// dispatching is true
+func (g *Group) popSpawned(dispatching bool) (Runner, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.spawned) == 0 {
+		if dispatching {
+			g.dispatching = false
+		}
+		return nil, false
+	}
+	runner := g.spawned[0]
+	g.spawned[0] = nil
+	g.spawned = g.spawned[1:]
+	return runner, true
+}
+
+// fail cancels the group with the error of dispatching a spawned runner
+func (g *Group) fail(err error) {
+	if err != nil && g.ctx.Err() == nil {
+		g.setErrOnce(err)
+		g.cancel()
+	}
+}
+
+// duplicate returns true if a runner with the same key has been submitted
+func (g *Group) duplicate(runner Runner) bool {
+	if g.dedupKey == nil {
+		return false
+	}
+	key := g.dedupKey(runner)
+	g.mu.Lock()
+	_, seen := g.seen[key]
+	if !seen {
+		if g.seen == nil {
+			g.seen = make(map[string]struct{})
+		}
+		g.seen[key] = struct{}{}
+	}
+	g.mu.Unlock()
+	if seen && g.logFunc != nil {
+		g.logFunc(&LogInfo{
+			Runner: runner,
+			Event:  Skip,
+		})
+	}
+	return seen
+}
+
+// forget removes the key of a runner failing to be dispatched
+func (g *Group) forget(runner Runner) {
+	if g.dedupKey == nil {
+		return
+	}
+	key := g.dedupKey(runner)
+	g.mu.Lock()
+	delete(g.seen, key)
+	g.mu.Unlock()
+}
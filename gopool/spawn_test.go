@@ -0,0 +1,296 @@
+package gopool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+// page is a runner crawling a fake site, whose page n links to the pages 2n
+// and 2n+1 as well as the home page 1
+type page struct {
+	n       int
+	max     int
+	visited *sync.Map
+}
+
+func (p *page) Name() string {
+	return fmt.Sprintf("page %d", p.n)
+}
+
+func (p *page) Run(ctx context.Context) error {
+	if _, loaded := p.visited.LoadOrStore(p.n, true); loaded {
+		return fmt.Errorf("page %d visited twice", p.n)
+	}
+	group := run.GroupFromContext(ctx)
+	for _, link := range []int{1, 2 * p.n, 2*p.n + 1} {
+		if link > p.max {
+			continue
+		}
+		if err := group.Spawn(&page{n: link, max: p.max, visited: p.visited}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGroupSpawn(t *testing.T) {
+	t.Parallel()
+
+	const max = 1000
+	pool := NewGoroutinePool(Max(2))
+	defer pool.Close()
+	var skipped int64
+	var mu sync.Mutex
+	g := NewGroup(context.Background(),
+		Pool(pool),
+		Dedup(func(runner Runner) string { return run.Name(runner) }),
+		Log(func(info *LogInfo) {
+			if info.Event == Skip {
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+			}
+		}),
+	)
+	visited := &sync.Map{}
+	if err := g.Go(&page{n: 1, max: max, visited: visited}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	visited.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	if count != max {
+		t.Fatalf("expect %d pages visited but got %d", max, count)
+	}
+	// every page links to the home page, which is visited already
+	if skipped != max {
+		t.Fatalf("expect %d skipped but got %d", max, skipped)
+	}
+}
+
+func TestGroupSpawnCancelled(t *testing.T) {
+	t.Parallel()
+
+	errFail := errors.New("fail")
+	g := NewGroup(context.Background())
+	if err := g.Go(run.Func(func(ctx context.Context) error {
+		return errFail
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != errFail {
+		t.Fatalf("expect %v but got %v", errFail, err)
+	}
+	if err := g.Spawn(run.Func(func(context.Context) error { return nil })); err != context.Canceled {
+		t.Fatalf("expect %v but got %v", context.Canceled, err)
+	}
+}
+
+func TestGroupSpawnDispatchError(t *testing.T) {
+	t.Parallel()
+
+	g := NewGroup(context.Background(), Pool(&closingPool{n: 1}))
+	if err := g.Go(run.Func(func(ctx context.Context) error {
+		if err := run.GroupFromContext(ctx).Spawn(run.Func(func(context.Context) error {
+			return nil
+		})); err != nil {
+			return err
+		}
+		// keep running, so that the spawned runner is dispatched
+		<-ctx.Done()
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != ErrClosed {
+		t.Fatalf("expect %v but got %v", ErrClosed, err)
+	}
+}
+
+func TestGroupSpawnBounded(t *testing.T) {
+	t.Parallel()
+
+	const spawned = 100
+	pool := &gatePool{gate: make(chan struct{}), entered: make(chan struct{}, spawned+1)}
+	var ran int64
+	drained := make(chan struct{})
+	g := NewGroup(context.Background(), Pool(pool))
+	if err := g.Go(run.Func(func(ctx context.Context) error {
+		group := run.GroupFromContext(ctx)
+		for i := 0; i < spawned; i++ {
+			if err := group.Spawn(run.Func(func(context.Context) error {
+				if atomic.AddInt64(&ran, 1) == spawned-1 {
+					close(drained)
+				}
+				return nil
+			})); err != nil {
+				return err
+			}
+		}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	// one spawned runner waits for the pool, while the others run on the
+	// goroutine of the exited runner
+	calls := 0
+	for drained != nil {
+		select {
+		case <-drained:
+			drained = nil
+		case <-pool.entered:
+			if calls++; calls > 2 {
+				t.Fatal("expect at most 1 goroutine dispatching the spawned runners")
+			}
+		}
+	}
+	close(pool.gate)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if ran != spawned {
+		t.Fatalf("expect %d spawned runners run but got %d", spawned, ran)
+	}
+}
+
+func TestGroupDedupDispatchError(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	g := NewGroup(ctx,
+		RateLimit(Limit{Rate: 1, Burst: 1, FailFast: true}),
+		Dedup(func(runner Runner) string { return fmt.Sprintf("%p", runner) }),
+	)
+	a, b := &runtest.FakeRunner{}, &runtest.FakeRunner{}
+	if err := g.Go(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Go(b); err != ErrRateLimited {
+		t.Fatalf("expect %v but got %v", ErrRateLimited, err)
+	}
+
+	// the runner failing to be dispatched is not a duplicate
+	clock.Advance(time.Second)
+	if err := g.Go(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := b.Calls(); n != 1 {
+		t.Fatalf("expect the retried runner run once but got %d", n)
+	}
+}
+
+func TestGroupSpawnDedup(t *testing.T) {
+	t.Parallel()
+
+	var skipped int64
+	g := NewGroup(context.Background(),
+		Dedup(func(runner Runner) string { return run.Name(runner) }),
+		Log(func(info *LogInfo) {
+			if info.Event == Skip {
+				atomic.AddInt64(&skipped, 1)
+			}
+		}),
+	)
+	child := &runtest.FakeRunner{}
+	if err := g.Go(run.Func(func(ctx context.Context) error {
+		group := run.GroupFromContext(ctx)
+		for i := 0; i < 10; i++ {
+			if err := group.Spawn(child); err != nil {
+				return err
+			}
+		}
+		// the duplicates are skipped by Spawn rather than queued
+		if n := atomic.LoadInt64(&skipped); n != 9 {
+			t.Errorf("expect 9 skipped when spawning but got %d", n)
+		}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := child.Calls(); n != 1 {
+		t.Fatalf("expect the spawned runner run once but got %d", n)
+	}
+}
+
+func TestGroupSpawnCancelQueued(t *testing.T) {
+	t.Parallel()
+
+	pool := NewGoroutinePool(Max(1))
+	defer pool.Close()
+	g := NewGroup(context.Background(), Pool(pool))
+	queued := &runtest.FakeRunner{}
+	if err := g.Go(run.Func(func(ctx context.Context) error {
+		group := run.GroupFromContext(ctx)
+		// the first one may be taken by the background dispatching
+		if err := group.Spawn(&runtest.FakeRunner{}); err != nil {
+			return err
+		}
+		if err := group.Spawn(queued); err != nil {
+			return err
+		}
+		g.Cancel()
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if n := queued.Calls(); n != 0 {
+		t.Fatalf("expect the queued runner dropped but got %d calls", n)
+	}
+}
+
+// gatePool starts a goroutine for the first task, and the others after gate
+// is closed
+type gatePool struct {
+	once    sync.Once
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func (p *gatePool) Go(ctx context.Context, fn func()) error {
+	p.entered <- struct{}{}
+	first := false
+	p.once.Do(func() { first = true })
+	if !first {
+		<-p.gate
+	}
+	go fn()
+	return nil
+}
+
+// closingPool starts n goroutines and then returns ErrClosed
+type closingPool struct {
+	n int64
+}
+
+func (p *closingPool) Go(ctx context.Context, fn func()) error {
+	if atomic.AddInt64(&p.n, -1) < 0 {
+		return ErrClosed
+	}
+	go fn()
+	return nil
+}
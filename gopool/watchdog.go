@@ -0,0 +1,131 @@
+package gopool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/internal/goroutine"
+)
+
+// Alert is reported by the watchdog of a group when a runner takes too long
+type Alert struct {
+	Runner         Runner
+	Reason         Reason
+	Elapsed        time.Duration // time elapsed since the runner started
+	SinceCancel    time.Duration // time elapsed since the context was cancelled, only set for IgnoreCancel
+	SinceHeartbeat time.Duration // time elapsed since the last heartbeat, only set for Hung
+	Stack          []byte        // stack of the goroutine executing the runner
+}
+
+// Reason enum of an alert
+type Reason int
+
+// Reason constants
+const (
+	Overrun      Reason = iota // runner runs longer than the timeout
+	IgnoreCancel               // runner keeps running after its context is cancelled
+	Hung                       // runner sends no heartbeat within the deadline
+)
+
+// String representation of int enum
+func (r Reason) String() string {
+	switch r {
+	case Overrun:
+		return "overrun"
+	case IgnoreCancel:
+		return "ignore cancel"
+	case Hung:
+		return "hung"
+	}
+	return ""
+}
+
+// RunnerName returns a meaningful name of the runner for logging
+func (a *Alert) RunnerName() string {
+	return logName(a.Runner)
+}
+
+// String provides a default string representation of the Alert
+func (a *Alert) String() string {
+	switch a.Reason {
+	case IgnoreCancel:
+		return fmt.Sprintf("%s keeps running for %v after cancelled\n%s", a.RunnerName(), a.SinceCancel, a.Stack)
+	case Hung:
+		return fmt.Sprintf("%s sends no heartbeat for %v\n%s", a.RunnerName(), a.SinceHeartbeat, a.Stack)
+	}
+	return fmt.Sprintf("%s keeps running for %v\n%s", a.RunnerName(), a.Elapsed, a.Stack)
+}
+
+// Watchdog specifies a watchdog for a group, which calls alert when a runner
+// runs longer than timeout, or keeps running longer than cancelTimeout after
+// its context is cancelled. A zero duration disables the corresponding check.
+//
+// A runner is reported at most once for each reason. The durations are
+// measured by the clock from run.ClockFromContext of the group context.
+func Watchdog(timeout, cancelTimeout time.Duration, alert func(*Alert)) GroupOption {
+	if alert == nil {
+		panic("watchdog alert should not be nil")
+	}
+	return func(g *Group) {
+		g.watchdog = &watchdog{
+			timeout:       timeout,
+			cancelTimeout: cancelTimeout,
+			alert:         alert,
+		}
+	}
+}
+
+type watchdog struct {
+	timeout       time.Duration
+	cancelTimeout time.Duration
+	alert         func(*Alert)
+}
+
+// watch starts watching the runner executed by goroutine gid with context
+// ctx, and returns a function to stop watching once the runner exits
+func (w *watchdog) watch(ctx context.Context, clock run.Clock, runner Runner, gid uint64) (stop func()) {
+	start := clock.Now()
+	exitChan := make(chan struct{})
+	var timer run.Timer
+	if w.timeout > 0 {
+		timer = clock.AfterFunc(w.timeout, func() {
+			w.alert(&Alert{
+				Runner:  runner,
+				Reason:  Overrun,
+				Elapsed: clock.Now().Sub(start),
+				Stack:   goroutine.Stack(gid),
+			})
+		})
+	}
+	if w.cancelTimeout > 0 {
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-exitChan:
+				return
+			}
+			cancelled := clock.Now()
+			timer := clock.NewTimer(w.cancelTimeout)
+			defer timer.Stop()
+			select {
+			case <-timer.C():
+				w.alert(&Alert{
+					Runner:      runner,
+					Reason:      IgnoreCancel,
+					Elapsed:     clock.Now().Sub(start),
+					SinceCancel: clock.Now().Sub(cancelled),
+					Stack:       goroutine.Stack(gid),
+				})
+			case <-exitChan:
+			}
+		}()
+	}
+	return func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		close(exitChan)
+	}
+}
@@ -0,0 +1,117 @@
+package gopool
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestWatchdogOverrun(t *testing.T) {
+	t.Parallel()
+
+	timeout := time.Second
+	clock := runtest.NewFakeClock(time.Now())
+	alertChan := make(chan *Alert, 1)
+	ctx := run.WithClock(context.Background(), clock)
+	group := NewGroup(ctx, Watchdog(timeout, 0, func(alert *Alert) {
+		alertChan <- alert
+	}))
+	runner := &runtest.FakeRunner{Block: true}
+	if err := group.Go(runner); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(timeout - time.Nanosecond)
+	select {
+	case alert := <-alertChan:
+		t.Fatalf("expect no alert before timeout but got %v", alert)
+	default:
+	}
+	clock.Advance(time.Nanosecond)
+	alert := <-alertChan
+	runner.Release()
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if alert.Reason != Overrun {
+		t.Fatalf("expect reason %v got %v", Overrun, alert.Reason)
+	}
+	if alert.Elapsed != timeout {
+		t.Fatalf("expect elapsed time %v but got %v", timeout, alert.Elapsed)
+	}
+	if !bytes.Contains(alert.Stack, []byte("FakeRunner")) {
+		t.Fatalf("expect the stack of the runner but got %s", alert.Stack)
+	}
+}
+
+func TestWatchdogIgnoreCancel(t *testing.T) {
+	t.Parallel()
+
+	alertChan := make(chan *Alert, 1)
+	group := NewGroup(context.Background(), Watchdog(0, time.Millisecond, func(alert *Alert) {
+		alertChan <- alert
+	}))
+	quitChan := make(chan struct{})
+	if err := group.Go(Func(func(context.Context) error {
+		<-quitChan // ignore ctx
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case alert := <-alertChan:
+		t.Fatalf("expect no alert before cancelled but got %v", alert)
+	case <-time.After(5 * time.Millisecond):
+	}
+
+	group.Cancel()
+	alert := <-alertChan
+	close(quitChan)
+	group.Wait()
+	if alert.Reason != IgnoreCancel {
+		t.Fatalf("expect reason %v got %v", IgnoreCancel, alert.Reason)
+	}
+	if alert.SinceCancel < time.Millisecond {
+		t.Fatalf("expect time since cancelled no less than 1ms but got %v", alert.SinceCancel)
+	}
+}
+
+func TestWatchdogNoAlert(t *testing.T) {
+	t.Parallel()
+
+	alertChan := make(chan *Alert, 2)
+	timeout := 50 * time.Millisecond
+	group := NewGroup(context.Background(), Watchdog(timeout, timeout, func(alert *Alert) {
+		alertChan <- alert
+	}))
+	if err := group.Go(Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	group.Cancel()
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case alert := <-alertChan:
+		t.Fatalf("expect no alert but got %v", alert)
+	case <-time.After(2 * timeout):
+	}
+}
+
+func TestWatchdogNilAlert(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect panic")
+		}
+	}()
+	Watchdog(time.Second, 0, nil)
+}
@@ -0,0 +1,268 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"h12.io/run"
+)
+
+const (
+	wheelBits   = 6
+	wheelSize   = 1 << wheelBits
+	wheelMask   = wheelSize - 1
+	wheelLevels = 6
+	// wheelSpan is the number of ticks covered by all the levels, tasks
+	// further than it are parked in the last slot and cascaded again
+	wheelSpan = 1 << (wheelBits * wheelLevels)
+)
+
+// TimingWheel is a runner that holds a large number of delayed tasks in a
+// hierarchical timing wheel, and dispatches the due tasks onto a pool.
+//
+// Unlike time.AfterFunc, no runtime timer is created per task: adding or
+// stopping a task is O(1) and the wheel itself ticks on a single timer. The
+// precision is one tick, so a task runs no earlier than its delay and no later
+// than the delay plus a tick.
+//
+// Each level of the wheel has 64 slots, a slot of a level covers all the slots
+// of the level below. A task is put into the lowest level that can hold its
+// delay and cascaded down to the lower levels when the wheel approaches it.
+type TimingWheel struct {
+	pool GroupPool
+	tick time.Duration
+
+	mu     sync.Mutex
+	now    uint64 // ticks since the wheel started
+	count  int
+	levels [wheelLevels][wheelSize]wheelSlot
+
+	// the clock and the time of the tick base while the wheel is running
+	clock run.Clock
+	start time.Time
+	base  uint64
+}
+
+// wheelSlot is the sentinel of a circular doubly linked list of tasks
+type wheelSlot struct {
+	head WheelTimer
+}
+
+// WheelTimer is a task added to a TimingWheel
+type WheelTimer struct {
+	wheel  *TimingWheel
+	fn     func()
+	expire uint64
+	slot   *wheelSlot
+	prev   *WheelTimer
+	next   *WheelTimer
+}
+
+// NewTimingWheel creates a new TimingWheel with the tick as its precision.
+// Due tasks are dispatched onto pool, if pool is nil, new goroutines are
+// started instead.
+func NewTimingWheel(pool GroupPool, tick time.Duration) *TimingWheel {
+	if tick <= 0 {
+		panic("tick should always be positive")
+	}
+	if pool == nil {
+		pool = dummyPool{}
+	}
+	w := &TimingWheel{
+		pool: pool,
+		tick: tick,
+	}
+	for l := range w.levels {
+		for s := range w.levels[l] {
+			head := &w.levels[l][s].head
+			head.prev = head
+			head.next = head
+		}
+	}
+	return w
+}
+
+// After schedules fn to be dispatched onto the pool after duration d, it can
+// be called before or while the wheel is running. The returned WheelTimer can
+// be used to cancel the call.
+func (w *TimingWheel) After(d time.Duration, fn func()) *WheelTimer {
+	if d < 0 {
+		d = 0
+	}
+	w.mu.Lock()
+	clock := w.clock
+	w.mu.Unlock()
+	var now time.Time
+	if clock != nil {
+		now = clock.Now()
+	}
+
+	t := &WheelTimer{wheel: w, fn: fn}
+	w.mu.Lock()
+	if w.clock != nil && w.clock == clock {
+		// count from the time rather than the last tick, which may be
+		// up to a tick earlier
+		t.expire = w.base + w.ticks(now.Sub(w.start)+d)
+	} else {
+		t.expire = w.now + w.ticks(d)
+	}
+	if t.expire <= w.now {
+		t.expire = w.now + 1
+	}
+	w.add(t)
+	w.count++
+	w.mu.Unlock()
+	return t
+}
+
+// ticks returns the number of the ticks covering d
+func (w *TimingWheel) ticks(d time.Duration) uint64 {
+	return uint64((d + w.tick - 1) / w.tick)
+}
+
+// Stop prevents the task from being dispatched, it returns false if the task
+// has already been dispatched or stopped
+func (t *WheelTimer) Stop() bool {
+	w := t.wheel
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if t.slot == nil {
+		return false
+	}
+	t.remove()
+	w.count--
+	return true
+}
+
+// Len returns the number of the pending tasks
+func (w *TimingWheel) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Run ticks the wheel and dispatches the due tasks until ctx is cancelled,
+// and then returns nil, even if ctx is cancelled while waiting for the pool,
+// or until dispatching a task fails otherwise, e.g. with ErrClosed, and then
+// returns the error. The tasks still pending, including the due ones not
+// dispatched yet, are left in the wheel and will be dispatched if the wheel is
+// run again. The time is measured by the clock from run.ClockFromContext.
+//
+// Dispatching blocks the wheel if the pool has reached its Max, so that the
+// tasks are throttled rather than piled up.
+func (w *TimingWheel) Run(ctx context.Context) error {
+	clock := run.ClockFromContext(ctx)
+	start := clock.Now()
+	w.mu.Lock()
+	base := w.now
+	w.clock, w.start, w.base = clock, start, base
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.clock = nil
+		w.mu.Unlock()
+	}()
+
+	timer := clock.NewTimer(w.tick)
+	defer timer.Stop()
+	var due []*WheelTimer
+	for {
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			return nil
+		}
+		elapsed := uint64(clock.Now().Sub(start) / w.tick)
+
+		w.mu.Lock()
+		for w.now < base+elapsed {
+			due = w.advance(due)
+		}
+		w.count -= len(due)
+		w.mu.Unlock()
+
+		for i, t := range due {
+			if err := w.pool.Go(ctx, t.fn); err != nil {
+				w.restore(due[i:])
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			due[i] = nil
+		}
+		due = due[:0]
+
+		next := time.Duration(elapsed+1)*w.tick - clock.Now().Sub(start)
+		timer.Reset(next)
+	}
+}
+
+// restore puts the due tasks failing to be dispatched back into the wheel,
+// to be due at the next tick
+func (w *TimingWheel) restore(due []*WheelTimer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, t := range due {
+		t.expire = w.now + 1
+		w.add(t)
+	}
+	w.count += len(due)
+}
+
+// add puts t into the slot matching its expiry, w.mu must be held
+func (w *TimingWheel) add(t *WheelTimer) {
+	delta := t.expire - w.now
+	expire := t.expire
+	if delta >= wheelSpan {
+		expire = w.now + wheelSpan - 1
+		delta = wheelSpan - 1
+	}
+	level := 0
+	for delta >= wheelSize && level < wheelLevels-1 {
+		delta >>= wheelBits
+		level++
+	}
+	slot := &w.levels[level][(expire>>(wheelBits*level))&wheelMask]
+	t.slot = slot
+	t.prev = slot.head.prev
+	t.next = &slot.head
+	slot.head.prev.next = t
+	slot.head.prev = t
+}
+
+// advance moves the wheel forward by one tick, cascades the higher levels
+// whose slot is reached, and appends the due tasks to due, w.mu must be held
+func (w *TimingWheel) advance(due []*WheelTimer) []*WheelTimer {
+	w.now++
+	for level := 1; level < wheelLevels; level++ {
+		if w.now&(1<<(wheelBits*level)-1) != 0 {
+			break
+		}
+		slot := &w.levels[level][(w.now>>(wheelBits*level))&wheelMask]
+		for t := slot.head.next; t != &slot.head; {
+			next := t.next
+			t.remove()
+			w.add(t)
+			t = next
+		}
+	}
+	slot := &w.levels[0][w.now&wheelMask]
+	for t := slot.head.next; t != &slot.head; {
+		next := t.next
+		t.remove()
+		due = append(due, t)
+		t = next
+	}
+	return due
+}
+
+// remove unlinks t from its slot
+func (t *WheelTimer) remove() {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev = nil
+	t.next = nil
+	t.slot = nil
+}
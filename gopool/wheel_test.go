@@ -0,0 +1,243 @@
+package gopool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestTimingWheel(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	clock := runtest.NewFakeClock(start)
+	ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+	defer cancel()
+	// run inline, so that the fake clock is not advanced before fn is done
+	wheel := NewTimingWheel(InlinePool{}, time.Millisecond)
+
+	delays := []time.Duration{
+		time.Millisecond,
+		50 * time.Millisecond,
+		64 * time.Millisecond,
+		time.Second,
+		5 * time.Second,
+		time.Hour,
+	}
+	var (
+		mu    sync.Mutex
+		fired = make(map[time.Duration]time.Duration)
+		wg    sync.WaitGroup
+	)
+	for _, d := range delays {
+		d := d
+		wg.Add(1)
+		wheel.After(d, func() {
+			mu.Lock()
+			fired[d] = clock.Now().Sub(start)
+			mu.Unlock()
+			wg.Done()
+		})
+	}
+	stopped := wheel.After(time.Second, func() {
+		t.Error("stopped task should not run")
+	})
+	if !stopped.Stop() {
+		t.Fatal("expect to stop a pending task")
+	}
+	if stopped.Stop() {
+		t.Fatal("expect to fail stopping a task twice")
+	}
+	if n := wheel.Len(); n != len(delays) {
+		t.Fatalf("expect %d pending tasks but got %d", len(delays), n)
+	}
+
+	errChan := make(chan error)
+	go func() { errChan <- wheel.Run(ctx) }()
+	elapsed := time.Duration(0)
+	for elapsed < time.Hour {
+		clock.BlockUntil(1)
+		step := 7 * time.Millisecond
+		if elapsed > 10*time.Second {
+			step = time.Minute
+		}
+		clock.Advance(step)
+		elapsed += step
+	}
+	wg.Wait()
+	cancel()
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, d := range delays {
+		got := fired[d]
+		// the precision is the advancing step in the test
+		tolerance := 7 * time.Millisecond
+		if d > 10*time.Second {
+			tolerance = time.Minute
+		}
+		if got < d || got > d+tolerance {
+			t.Fatalf("expect task after %v to run at %v but got %v", d, d, got)
+		}
+	}
+	if n := wheel.Len(); n != 0 {
+		t.Fatalf("expect no pending tasks but got %d", n)
+	}
+}
+
+func TestTimingWheelDispatchError(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+	pool := &stallingPool{stalled: make(chan struct{})}
+	wheel := NewTimingWheel(pool, time.Millisecond)
+	var ran int64
+	for i := 0; i < 3; i++ {
+		wheel.After(time.Millisecond, func() { atomic.AddInt64(&ran, 1) })
+	}
+
+	// the first task stalls in dispatching until the wheel is cancelled, which
+	// is not an error
+	errChan := make(chan error)
+	go func() { errChan <- wheel.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Millisecond)
+	<-pool.stalled
+	cancel()
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if n := wheel.Len(); n != 3 {
+		t.Fatalf("expect 3 pending tasks but got %d", n)
+	}
+
+	// the tasks are dispatched when the wheel runs again
+	ctx, cancel = context.WithCancel(run.WithClock(context.Background(), clock))
+	go func() { errChan <- wheel.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Millisecond)
+	clock.BlockUntil(1)
+	cancel()
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&ran); n != 3 {
+		t.Fatalf("expect 3 tasks run but got %d", n)
+	}
+	if n := wheel.Len(); n != 0 {
+		t.Fatalf("expect no pending tasks but got %d", n)
+	}
+}
+
+func TestTimingWheelDispatchClosed(t *testing.T) {
+	t.Parallel()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+	defer cancel()
+	pool := NewGoroutinePool()
+	pool.Close()
+	wheel := NewTimingWheel(pool, time.Millisecond)
+	wheel.After(time.Millisecond, func() {})
+
+	errChan := make(chan error)
+	go func() { errChan <- wheel.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Millisecond)
+	if err := <-errChan; err != ErrClosed {
+		t.Fatalf("expect %v but got %v", ErrClosed, err)
+	}
+	if n := wheel.Len(); n != 1 {
+		t.Fatalf("expect 1 pending task but got %d", n)
+	}
+}
+
+func TestTimingWheelMidTick(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	clock := runtest.NewFakeClock(start)
+	ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+	defer cancel()
+	wheel := NewTimingWheel(InlinePool{}, time.Second)
+	var fired int64 // the elapsed time when the task runs
+	errChan := make(chan error)
+	go func() { errChan <- wheel.Run(ctx) }()
+
+	// a task added just before the first tick must not run at the tick
+	clock.BlockUntil(1)
+	clock.Advance(990 * time.Millisecond)
+	wheel.After(time.Second, func() {
+		atomic.StoreInt64(&fired, int64(clock.Now().Sub(start)))
+	})
+	clock.Advance(10 * time.Millisecond)
+	clock.BlockUntil(1)
+	if got := atomic.LoadInt64(&fired); got != 0 {
+		t.Fatalf("expect the task not to run yet but it ran at %v", time.Duration(got))
+	}
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	if got := time.Duration(atomic.LoadInt64(&fired)); got != 2*time.Second {
+		t.Fatalf("expect the task to run at %v but got %v", 2*time.Second, got)
+	}
+	cancel()
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stallingPool stalls the first dispatching until ctx is cancelled, and runs
+// the others inline
+type stallingPool struct {
+	once    sync.Once
+	stalled chan struct{}
+}
+
+func (p *stallingPool) Go(ctx context.Context, fn func()) error {
+	stall := false
+	p.once.Do(func() { stall = true })
+	if stall {
+		close(p.stalled)
+		<-ctx.Done()
+		return ErrDispatchTimeout
+	}
+	fn()
+	return nil
+}
+
+func TestTimingWheelCascade(t *testing.T) {
+	t.Parallel()
+
+	wheel := NewTimingWheel(nil, time.Millisecond)
+	var due []*WheelTimer
+	expires := []uint64{1, 63, 64, 65, 4095, 4096, 4097, 300000, 17000000}
+	for _, expire := range expires {
+		wheel.After(time.Duration(expire)*time.Millisecond, func() {})
+	}
+	for _, expire := range expires {
+		for wheel.now < expire {
+			if due = wheel.advance(due[:0]); len(due) > 0 && wheel.now != expire {
+				t.Fatalf("expect task at %d but run at %d", expire, wheel.now)
+			}
+		}
+		if len(due) != 1 || due[0].expire != expire {
+			t.Fatalf("expect task at %d to be due", expire)
+		}
+	}
+}
+
+func BenchmarkTimingWheelAfter(b *testing.B) {
+	wheel := NewTimingWheel(nil, time.Millisecond)
+	fn := func() {}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wheel.After(time.Duration(i%100000)*time.Millisecond, fn).Stop()
+	}
+}
@@ -0,0 +1,25 @@
+package run
+
+import "context"
+
+// Spawner submits runners into a group, e.g. gopool.Group, without waiting
+// for them, so that a running runner can add more work to its own group
+type Spawner interface {
+	Spawn(runner Runner) error
+}
+
+type groupKey struct{}
+
+// WithGroup returns a copy of ctx carrying the group that runs the runners
+// with the returned context
+func WithGroup(ctx context.Context, group Spawner) context.Context {
+	return context.WithValue(ctx, groupKey{}, group)
+}
+
+// GroupFromContext returns the group running the runner with ctx, or nil if
+// there is none. A runner of a gopool.Group can spawn its siblings through it,
+// e.g. a crawler submitting the links it finds.
+func GroupFromContext(ctx context.Context) Spawner {
+	group, _ := ctx.Value(groupKey{}).(Spawner)
+	return group
+}
@@ -0,0 +1,21 @@
+package run
+
+import "context"
+
+type heartbeatKey struct{}
+
+// Heartbeat reports that the runner owning ctx is still making progress. A
+// long-running runner supervised by heartbeat should call it periodically
+// within its loop. It is a no-op if ctx is not supervised.
+func Heartbeat(ctx context.Context) {
+	if beat, ok := ctx.Value(heartbeatKey{}).(func()); ok {
+		beat()
+	}
+}
+
+// WithHeartbeat returns a copy of ctx, so that a call to Heartbeat with the
+// returned context (or its descendants) calls beat. It is meant to be used
+// by the supervisor of a runner.
+func WithHeartbeat(ctx context.Context, beat func()) context.Context {
+	return context.WithValue(ctx, heartbeatKey{}, beat)
+}
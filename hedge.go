@@ -0,0 +1,200 @@
+package run
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeDelay decides how long to wait for an attempt before launching a
+// hedged one
+type HedgeDelay interface {
+	// Delay returns the delay before launching the next attempt
+	Delay() time.Duration
+	// Observe records the latency of an attempt, including the ones failed or
+	// cancelled, whose latency is the time until they exit
+	Observe(latency time.Duration)
+}
+
+// FixedDelay returns a HedgeDelay that always waits for d
+func FixedDelay(d time.Duration) HedgeDelay {
+	return fixedDelay(d)
+}
+
+type fixedDelay time.Duration
+
+func (d fixedDelay) Delay() time.Duration  { return time.Duration(d) }
+func (fixedDelay) Observe(d time.Duration) {}
+
+// PercentileDelay returns a HedgeDelay that waits for the p-th percentile
+// (within (0, 1], e.g. 0.95) of the latencies of the most recent window
+// attempts, so that only the slowest attempts are hedged. initial
+// is used before any latency is observed.
+func PercentileDelay(p float64, window int, initial time.Duration) HedgeDelay {
+	if p <= 0 || p > 1 {
+		panic("percentile should be within (0, 1]")
+	}
+	if window <= 0 {
+		panic("window should always be positive")
+	}
+	return &percentileDelay{
+		p:         p,
+		initial:   initial,
+		latencies: make([]time.Duration, 0, window),
+	}
+}
+
+type percentileDelay struct {
+	p       float64
+	initial time.Duration
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of the recent latencies
+	next      int
+}
+
+func (d *percentileDelay) Delay() time.Duration {
+	d.mu.Lock()
+	if len(d.latencies) == 0 {
+		d.mu.Unlock()
+		return d.initial
+	}
+	sorted := append([]time.Duration(nil), d.latencies...)
+	d.mu.Unlock()
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(float64(len(sorted))*d.p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func (d *percentileDelay) Observe(latency time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.latencies) < cap(d.latencies) {
+		d.latencies = append(d.latencies, latency)
+		return
+	}
+	d.latencies[d.next] = latency
+	d.next = (d.next + 1) % len(d.latencies)
+}
+
+// Hedged is a runner that hedges the attempts of a runner, see Hedge
+type Hedged struct {
+	factory   func(attempt int) Runner
+	delay     HedgeDelay
+	maxHedges int
+}
+
+// Hedge returns a runner that reduces the tail latency by hedged requests:
+// it runs the runner created by factory with attempt 0, and if it has not
+// succeeded after the delay, launches another attempt created by factory with
+// attempt 1, and so on, up to maxHedges attempts in addition to the first
+// one. A failed attempt launches the next one immediately.
+//
+// The first attempt to succeed wins, the others are cancelled, and nil is
+// returned after all the attempts exit. If all the attempts fail, their
+// errors are returned as Errors in the order of their exits.
+//
+// The attempts are dispatched onto the pool from PoolFromContext, and the
+// delay is measured by the clock from ClockFromContext.
+func Hedge(factory func(attempt int) Runner, delay HedgeDelay, maxHedges int) *Hedged {
+	if maxHedges < 0 {
+		panic("maxHedges should not be negative")
+	}
+	return &Hedged{
+		factory:   factory,
+		delay:     delay,
+		maxHedges: maxHedges,
+	}
+}
+
+// Name returns the name of the factory
+func (h *Hedged) Name() string {
+	return "hedge(" + Name(h.factory) + ")"
+}
+
+// Run runs the hedged attempts, see Do for retrieving the result
+func (h *Hedged) Run(ctx context.Context) error {
+	_, err := h.Do(ctx)
+	return err
+}
+
+// Do runs the hedged attempts like Run, and returns the runner of the winning
+// attempt, so that its result can be retrieved. The winner is returned per
+// call, so a Hedged can be run concurrently or repeatedly.
+func (h *Hedged) Do(ctx context.Context) (Runner, error) {
+	clock := ClockFromContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		runner Runner
+		start  time.Time
+		err    error
+	}
+	results := make(chan result, h.maxHedges+1)
+	launched, running := 0, 0
+	launch := func() {
+		runner := h.factory(launched)
+		launched++
+		running++
+		start := clock.Now()
+		if err := goFunc(ctx, func() {
+			results <- result{runner: runner, start: start, err: runner.Run(ctx)}
+		}); err != nil {
+			results <- result{runner: runner, start: start, err: err}
+		}
+	}
+
+	var (
+		winner Runner
+		errs   Errors
+		timer  Timer
+	)
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
+	defer stopTimer()
+	launch()
+	for running > 0 {
+		var timerChan <-chan time.Time
+		if winner == nil && launched <= h.maxHedges && ctx.Err() == nil {
+			if timer == nil {
+				timer = clock.NewTimer(h.delay.Delay())
+			}
+			timerChan = timer.C()
+		}
+		select {
+		case <-timerChan:
+			timer = nil
+			launch()
+		case r := <-results:
+			running--
+			h.delay.Observe(clock.Now().Sub(r.start))
+			switch {
+			case winner != nil:
+				// a loser
+			case r.err == nil:
+				winner = r.runner
+				stopTimer()
+				cancel()
+			default:
+				errs = append(errs, r.err)
+				if launched <= h.maxHedges && ctx.Err() == nil {
+					stopTimer()
+					launch()
+				}
+			}
+		}
+	}
+	if winner == nil {
+		return nil, errs
+	}
+	return winner, nil
+}
@@ -0,0 +1,137 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestHedge(t *testing.T) {
+	defer runtest.Check(t)()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx := run.WithClock(context.Background(), clock)
+	runners := []*runtest.FakeRunner{{Block: true}, {Block: true}, {}}
+	delay := &countingDelay{HedgeDelay: run.FixedDelay(time.Second)}
+	hedge := run.Hedge(func(attempt int) run.Runner {
+		return runners[attempt]
+	}, delay, 2)
+
+	type result struct {
+		winner run.Runner
+		err    error
+	}
+	resultChan := make(chan result)
+	go func() {
+		winner, err := hedge.Do(ctx)
+		resultChan <- result{winner, err}
+	}()
+	<-runners[0].Started()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-runners[1].Started()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	r := <-resultChan
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.winner != runners[2] {
+		t.Fatalf("expect the third attempt to win but got %v", r.winner)
+	}
+	// the latencies of the cancelled attempts are observed as well
+	if n := delay.observed(); n != 3 {
+		t.Fatalf("expect 3 latencies observed but got %d", n)
+	}
+}
+
+// countingDelay counts the observed latencies
+type countingDelay struct {
+	run.HedgeDelay
+	mu sync.Mutex
+	n  int
+}
+
+func (d *countingDelay) Observe(latency time.Duration) {
+	d.mu.Lock()
+	d.n++
+	d.mu.Unlock()
+	d.HedgeDelay.Observe(latency)
+}
+
+func (d *countingDelay) observed() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.n
+}
+
+func TestHedgeFailure(t *testing.T) {
+	defer runtest.Check(t)()
+
+	errRun := errors.New("err run")
+	attempts := 0
+	hedge := run.Hedge(func(attempt int) run.Runner {
+		attempts++
+		return &runtest.FakeRunner{Err: errRun}
+	}, run.FixedDelay(time.Hour), 2)
+	winner, err := hedge.Do(context.Background())
+	var errs run.Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expect 3 errors but got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expect failed attempts to launch the next immediately, but got %d attempts", attempts)
+	}
+	if winner != nil {
+		t.Fatalf("expect no winner but got %v", winner)
+	}
+}
+
+func TestHedgeMax(t *testing.T) {
+	defer runtest.Check(t)()
+
+	clock := runtest.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(run.WithClock(context.Background(), clock))
+	runner := &runtest.FakeRunner{Block: true}
+	attempts := 0
+	hedge := run.Hedge(func(attempt int) run.Runner {
+		attempts++
+		return runner
+	}, run.FixedDelay(time.Second), 1)
+	errChan := make(chan error)
+	go func() { errChan <- hedge.Run(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	for runner.Calls() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Hour)
+	cancel()
+	if err := <-errChan; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect error %v but got %v", context.Canceled, err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expect 2 attempts but got %d", attempts)
+	}
+}
+
+func TestPercentileDelay(t *testing.T) {
+	t.Parallel()
+
+	delay := run.PercentileDelay(0.9, 10, time.Second)
+	if d := delay.Delay(); d != time.Second {
+		t.Fatalf("expect initial delay %v but got %v", time.Second, d)
+	}
+	for i := 1; i <= 20; i++ {
+		delay.Observe(time.Duration(i) * time.Millisecond)
+	}
+	// window holds 11ms..20ms
+	if d := delay.Delay(); d != 19*time.Millisecond {
+		t.Fatalf("expect delay %v but got %v", 19*time.Millisecond, d)
+	}
+}
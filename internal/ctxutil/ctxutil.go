@@ -0,0 +1,23 @@
+// Package ctxutil provides context helpers shared by the packages of the
+// module.
+package ctxutil
+
+import "context"
+
+// detachedContext carries the values of its parent without its cancellation
+// or deadline
+type detachedContext struct {
+	context.Context
+	parent context.Context
+}
+
+// Detach returns a context carrying the values of parent, which is never
+// cancelled and has no deadline, so that the work started with it can
+// outlive parent
+func Detach(parent context.Context) context.Context {
+	return detachedContext{Context: context.Background(), parent: parent}
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -1,4 +1,5 @@
-package gopool
+// Package goroutine provides goroutine IDs and stacks for debugging purpose.
+package goroutine
 
 import (
 	"bytes"
@@ -18,23 +19,58 @@ var littleBuf = sync.Pool{
 	},
 }
 
-func getGID() uint64 {
+// ID returns the ID of the current goroutine
+func ID() uint64 {
 	bp := littleBuf.Get().(*[]byte)
 	defer littleBuf.Put(bp)
 	b := *bp
 	b = b[:runtime.Stack(b, false)]
+	n, err := parseID(b)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// parseID parses the goroutine ID out of the header of a goroutine stack
+func parseID(b []byte) (uint64, error) {
 	// Parse the 4707 out of "goroutine 4707 ["
 	b = bytes.TrimPrefix(b, goroutineSpace)
 	i := bytes.IndexByte(b, ' ')
 	if i < 0 {
-		panic(fmt.Sprintf("No space found in %q", b))
+		return 0, fmt.Errorf("no space found in %q", b)
 	}
 	b = b[:i]
 	n, err := parseUintBytes(b, 10, 64)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to parse goroutine ID out of %q: %v", b, err))
+		return 0, fmt.Errorf("failed to parse goroutine ID out of %q: %v", b, err)
 	}
-	return n
+	return n, nil
+}
+
+// Stacks returns the stacks of all goroutines keyed by their IDs
+func Stacks() map[uint64][]byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	stacks := make(map[uint64][]byte)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if id, err := parseID(stack); err == nil {
+			stacks[id] = stack
+		}
+	}
+	return stacks
+}
+
+// Stack returns the stack of the goroutine id
+func Stack(id uint64) []byte {
+	return Stacks()[id]
 }
 
 // parseUintBytes is like strconv.ParseUint, but using a []byte.
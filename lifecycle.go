@@ -0,0 +1,39 @@
+package run
+
+import "context"
+
+// Readier is an optional interface of a runner that takes time to be ready
+// after it starts, e.g. a server that is ready once it is listening. Group,
+// Handle and Closer recognise it.
+type Readier interface {
+	// Ready returns a channel that is closed when the runner is ready
+	Ready() <-chan struct{}
+}
+
+// Stopper is an optional interface of a runner that supports graceful
+// stopping, e.g. a server that finishes the in-flight requests before it
+// exits. Group, Handle and Closer call Stop before cancelling the context of
+// the runner.
+type Stopper interface {
+	// Stop requests the runner to drain and returns when it is drained or
+	// ctx is done, after which the Run method of the runner should return
+	// without its context being cancelled
+	Stop(ctx context.Context) error
+}
+
+// Admitter is an optional interface of a runner that may reject a run before
+// it starts, e.g. an open circuit breaker. Group calls Admit before
+// dispatching the runner, so that a rejected run does not take a goroutine.
+// A runner wrapping another one, e.g. WithTimeout, forwards it.
+type Admitter interface {
+	// Admit returns the error that a run would fail with right now, or nil
+	// if the run is allowed. It does not start or reserve the run.
+	Admit(ctx context.Context) error
+}
+
+// closedChan is a closed channel returned for the runners always ready
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
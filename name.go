@@ -0,0 +1,34 @@
+package run
+
+import (
+	"reflect"
+	"runtime"
+)
+
+type namer interface {
+	Name() string
+}
+
+// Name tries to get a meaningful name of a variable for logging purpose,
+// meant to be used for logging the name of a runner.
+//
+// If it provides a Name() string method, it is returned.
+// If it is a function (e.g. run.Func), the full name of the function is
+// returned.
+// Otherwise, the full name of the concrete type is returned.
+func Name(runner interface{}) string {
+	if runner == nil {
+		return "nil"
+	}
+	if n, ok := runner.(namer); ok {
+		return n.Name()
+	}
+	typ := reflect.TypeOf(runner)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Func {
+		return runtime.FuncForPC(reflect.ValueOf(runner).Pointer()).Name()
+	}
+	return typ.PkgPath() + "." + typ.Name()
+}
@@ -0,0 +1,38 @@
+package run
+
+import "context"
+
+// Pool is an interface for a goroutine pool, e.g. gopool.GoroutinePool, used
+// by the combinators to run their runners concurrently
+type Pool interface {
+	Go(ctx context.Context, fn func()) error
+}
+
+type poolKey struct{}
+
+// WithPool returns a copy of ctx carrying pool, so that the combinators
+// running with the returned context (or its descendants) dispatch their
+// runners onto pool.
+//
+// Combinators nested within a runner dispatched onto a pool with a Max limit
+// may wait for a goroutine held by their parent, so the pool should be large
+// enough for the nesting depth.
+func WithPool(ctx context.Context, pool Pool) context.Context {
+	return context.WithValue(ctx, poolKey{}, pool)
+}
+
+// PoolFromContext returns the pool carried by ctx, or nil if there is none
+func PoolFromContext(ctx context.Context) Pool {
+	pool, _ := ctx.Value(poolKey{}).(Pool)
+	return pool
+}
+
+// goFunc runs fn on the pool carried by ctx, or in a new goroutine if there
+// is none
+func goFunc(ctx context.Context, fn func()) error {
+	if pool := PoolFromContext(ctx); pool != nil {
+		return pool.Go(ctx, fn)
+	}
+	go fn()
+	return nil
+}
@@ -0,0 +1,267 @@
+// Package proc provides a runner running a subprocess, which is terminated
+// gracefully when the runner is cancelled.
+package proc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/gopool"
+)
+
+// Stream is the output stream of a subprocess
+type Stream int
+
+// Stream constants
+const (
+	Stdout Stream = iota + 1
+	Stderr
+)
+
+// String representation of int enum
+func (s Stream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	}
+	return ""
+}
+
+// ExitError is returned when a subprocess exits with a non-zero status or is
+// terminated by a signal
+type ExitError struct {
+	Name   string
+	Code   int       // exit code, or -1 if terminated by a signal
+	Signal os.Signal // signal terminating the process, or nil
+	Killed bool      // true if killed after the grace period
+	Err    *exec.ExitError
+}
+
+// Error satisfies error interface
+func (e *ExitError) Error() string {
+	switch {
+	case e.Killed:
+		return fmt.Sprintf("%s killed after the grace period", e.Name)
+	case e.Signal != nil:
+		return fmt.Sprintf("%s terminated by signal %v", e.Name, e.Signal)
+	}
+	return fmt.Sprintf("%s exited with code %d", e.Name, e.Code)
+}
+
+// Unwrap returns the underlying *exec.ExitError
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// Cmd is a runner running a subprocess, each run starts a new process.
+//
+// When the runner is cancelled, the process and its children in the same
+// process group are sent SIGTERM, and if they do not exit within the grace
+// period, SIGKILL. On Windows, the process is killed immediately.
+type Cmd struct {
+	Path  string        // the command to run, looked up in PATH if it has no separators
+	Args  []string      // arguments, excluding the command
+	Dir   string        // working directory, if empty, the current directory is used
+	Env   []string      // environment in the form "key=value", if nil, the current environment is used
+	Grace time.Duration // grace period between SIGTERM and SIGKILL, default to 10s
+
+	// WaitDelay is the period for the output to be drained after the process
+	// exits, default to 1s. After that, the output is closed even if it is
+	// still held open by a child of the process running in the background.
+	WaitDelay time.Duration
+
+	// Output is called with each line of stdout and stderr, without the line
+	// ending. If nil, the lines are logged by gopool.LogOutput, or the
+	// standard logger if the runner is not running in a group with a log
+	// function.
+	Output func(stream Stream, line string)
+
+	mu      sync.Mutex
+	process *os.Process
+}
+
+// ErrNotRunning is returned by Signal when the process is not running
+var ErrNotRunning = errors.New("proc: process not running")
+
+// Command returns a Cmd running name with args
+func Command(name string, args ...string) *Cmd {
+	return &Cmd{Path: name, Args: args}
+}
+
+// Name returns the base name of the command
+func (c *Cmd) Name() string {
+	return filepath.Base(c.Path)
+}
+
+// Run starts the process and waits for its exit. It returns nil if the
+// process exits with zero status, or is terminated by the SIGTERM sent on
+// cancellation. Otherwise an *ExitError is returned for a non-zero exit
+// status, including one exiting within the grace period, or the error of
+// starting the process.
+func (c *Cmd) Run(ctx context.Context) error {
+	cmd := exec.Command(c.Path, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Env = c.Env
+	setProcessGroup(cmd)
+	// the pipes are files rather than the ones of cmd, so that Wait returns
+	// once the process exits, without waiting for the output to be closed
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stderr, stderrW, err := os.Pipe()
+	if err != nil {
+		stdout.Close()
+		stdoutW.Close()
+		return err
+	}
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	err = cmd.Start()
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		return err
+	}
+	c.setProcess(cmd.Process)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go c.scan(ctx, Stdout, stdout, &wg)
+	go c.scan(ctx, Stderr, stderr, &wg)
+	waitChan := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		c.setProcess(nil)
+		c.drain(ctx, &wg, stdout, stderr)
+		waitChan <- err
+	}()
+
+	select {
+	case err := <-waitChan:
+		return c.exitError(err, false)
+	case <-ctx.Done():
+	}
+
+	terminate(cmd)
+	grace := c.Grace
+	if grace <= 0 {
+		grace = 10 * time.Second
+	}
+	timer := run.ClockFromContext(ctx).NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case err := <-waitChan:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && terminated(exitErr) {
+			return nil
+		}
+		return c.exitError(err, false)
+	case <-timer.C():
+	}
+	kill(cmd)
+	return c.exitError(<-waitChan, true)
+}
+
+func (c *Cmd) setProcess(p *os.Process) {
+	c.mu.Lock()
+	c.process = p
+	c.mu.Unlock()
+}
+
+// Pid returns the process ID of the running process, or 0 if it is not
+// running
+func (c *Cmd) Pid() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.process == nil {
+		return 0
+	}
+	return c.process.Pid
+}
+
+// Signal sends sig to the running process, it returns ErrNotRunning if the
+// process is not running
+func (c *Cmd) Signal(sig os.Signal) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.process == nil {
+		return ErrNotRunning
+	}
+	return c.process.Signal(sig)
+}
+
+func (c *Cmd) exitError(err error, killed bool) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	return &ExitError{
+		Name:   c.Name(),
+		Code:   exitErr.ExitCode(),
+		Signal: exitSignal(exitErr),
+		Killed: killed,
+		Err:    exitErr,
+	}
+}
+
+// drain waits for the output to be read up to WaitDelay, and then closes it
+func (c *Cmd) drain(ctx context.Context, wg *sync.WaitGroup, files ...*os.File) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	delay := c.WaitDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	timer := run.ClockFromContext(ctx).NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C():
+	}
+	for _, f := range files {
+		f.Close()
+	}
+	<-done
+}
+
+// scan reads r line by line and outputs the lines
+func (c *Cmd) scan(ctx context.Context, stream Stream, r io.Reader, wg *sync.WaitGroup) {
+	defer wg.Done()
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			c.output(ctx, stream, strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *Cmd) output(ctx context.Context, stream Stream, line string) {
+	if c.Output != nil {
+		c.Output(stream, line)
+		return
+	}
+	if !gopool.LogOutput(ctx, c, line) {
+		log.Printf("%s %v: %s", c.Name(), stream, line)
+	}
+}
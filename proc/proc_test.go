@@ -0,0 +1,161 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"h12.io/run/gopool"
+)
+
+func TestCmdOutput(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		lines = make(map[Stream][]string)
+	)
+	cmd := Command("sh", "-c", "echo out1; echo err >&2; printf out2")
+	cmd.Output = func(stream Stream, line string) {
+		mu.Lock()
+		lines[stream] = append(lines[stream], line)
+		mu.Unlock()
+	}
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[Stream][]string{
+		Stdout: {"out1", "out2"},
+		Stderr: {"err"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("expect %v got %v", want, lines)
+	}
+}
+
+func TestCmdExitCode(t *testing.T) {
+	t.Parallel()
+
+	err := Command("sh", "-c", "exit 3").Run(context.Background())
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || exitErr.Signal != nil {
+		t.Fatalf("expect exit code 3 but got %v", err)
+	}
+}
+
+func TestCmdTerminate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name       string
+		script     string
+		wantCode   int
+		wantKilled bool
+	}{
+		{
+			name:   "graceful",
+			script: `trap "exit 0" TERM; echo ready; while true; do sleep 0.01; done`,
+		},
+		{
+			name:   "sigterm",
+			script: `echo ready; while true; do sleep 0.01; done`,
+		},
+		{
+			name:     "failed",
+			script:   `trap "exit 3" TERM; echo ready; while true; do sleep 0.01; done`,
+			wantCode: 3,
+		},
+		{
+			name:       "killed",
+			script:     `trap "" TERM; echo ready; sleep 10`,
+			wantKilled: true,
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ready := make(chan struct{})
+			var once sync.Once
+			cmd := Command("sh", "-c", tc.script)
+			cmd.Grace = 100 * time.Millisecond
+			cmd.Output = func(stream Stream, line string) {
+				once.Do(func() { close(ready) })
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			errChan := make(chan error)
+			go func() { errChan <- cmd.Run(ctx) }()
+			<-ready
+			cancel()
+			err := <-errChan
+			var exitErr *ExitError
+			if tc.wantCode != 0 {
+				if !errors.As(err, &exitErr) || exitErr.Code != tc.wantCode || exitErr.Killed {
+					t.Fatalf("expect exit code %d but got %v", tc.wantCode, err)
+				}
+				return
+			}
+			if !tc.wantKilled {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.As(err, &exitErr) || !exitErr.Killed || exitErr.Signal != syscall.SIGKILL {
+				t.Fatalf("expect killed by SIGKILL but got %v", err)
+			}
+		})
+	}
+}
+
+func TestCmdBackgroundChild(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	cmd := Command("sh", "-c", "sleep 3 & echo started")
+	cmd.WaitDelay = 10 * time.Millisecond
+	cmd.Output = func(stream Stream, line string) {
+		lines = append(lines, line)
+	}
+	start := time.Now()
+	if err := cmd.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the child holding the output does not keep Run blocked
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expect Run to return once the process exits but took %v", elapsed)
+	}
+	if !reflect.DeepEqual(lines, []string{"started"}) {
+		t.Fatalf("expect the output drained but got %v", lines)
+	}
+	if pid := cmd.Pid(); pid != 0 {
+		t.Fatalf("expect no pid after exit but got %d", pid)
+	}
+}
+
+func TestCmdGroupLog(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	group := gopool.NewGroup(context.Background(), gopool.Log(func(info *gopool.LogInfo) {
+		if info.Event == gopool.Output {
+			lines = append(lines, info.String())
+		}
+	}))
+	if err := group.Go(Command("echo", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"echo outputs: hello"}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("expect %v got %v", want, lines)
+	}
+}
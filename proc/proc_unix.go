@@ -0,0 +1,39 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the process in a new process group, so that its
+// children are signalled along with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate sends SIGTERM to the process group
+func terminate(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// kill sends SIGKILL to the process group
+func kill(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// terminated reports whether the process is terminated by SIGTERM
+func terminated(err *exec.ExitError) bool {
+	return exitSignal(err) == syscall.SIGTERM
+}
+
+// exitSignal returns the signal that terminated the process, or nil
+func exitSignal(err *exec.ExitError) os.Signal {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal()
+	}
+	return nil
+}
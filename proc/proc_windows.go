@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the process, as Windows does not support SIGTERM
+func terminate(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// kill kills the process
+func kill(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+// terminated always returns true, as the process is killed by terminate and
+// the exit status does not tell it apart
+func terminated(err *exec.ExitError) bool {
+	return true
+}
+
+// exitSignal always returns nil, as Windows does not support signals
+func exitSignal(err *exec.ExitError) os.Signal {
+	return nil
+}
@@ -0,0 +1,70 @@
+package runtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockTimer(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	clock := NewFakeClock(start)
+	timer := clock.NewTimer(time.Second)
+	clock.Advance(time.Second - time.Nanosecond)
+	select {
+	case <-timer.C():
+		t.Fatal("expect timer not fired before its expiry")
+	default:
+	}
+	clock.Advance(time.Nanosecond)
+	if now := <-timer.C(); !now.Equal(start.Add(time.Second)) {
+		t.Fatalf("expect fired at %v got %v", start.Add(time.Second), now)
+	}
+	if timer.Stop() {
+		t.Fatal("expect Stop returns false after fired")
+	}
+	if timer.Reset(time.Second) {
+		t.Fatal("expect Reset returns false after fired")
+	}
+	if !timer.Stop() {
+		t.Fatal("expect Stop returns true for an active timer")
+	}
+	clock.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatal("expect stopped timer not fired")
+	default:
+	}
+}
+
+func TestFakeClockAfterFunc(t *testing.T) {
+	t.Parallel()
+
+	clock := NewFakeClock(time.Now())
+	var fired []int
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, 1) })
+	clock.AfterFunc(3*time.Second, func() { fired = append(fired, 3) })
+	clock.Advance(2 * time.Second)
+	if len(fired) != 2 || fired[0] != 1 || fired[1] != 2 {
+		t.Fatalf("expect timers fired in order [1 2] but got %v", fired)
+	}
+	if n := clock.Timers(); n != 1 {
+		t.Fatalf("expect 1 timer left but got %d", n)
+	}
+}
+
+func TestFakeClockBlockUntil(t *testing.T) {
+	t.Parallel()
+
+	clock := NewFakeClock(time.Now())
+	done := make(chan struct{})
+	go func() {
+		<-clock.NewTimer(time.Second).C()
+		close(done)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-done
+}
@@ -0,0 +1,112 @@
+// Package runtest provides utilities for testing runners, groups and pools.
+package runtest
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"h12.io/run/internal/goroutine"
+)
+
+// Baseline is a snapshot of the goroutines running at a point of a test,
+// against which goroutine leaks are detected afterwards.
+//
+// Goroutines started by other tests running in parallel are also considered
+// leaked, so a test using Baseline should not call t.Parallel.
+type Baseline struct {
+	// Timeout is the maximum time to wait for the goroutines to exit,
+	// default to 1s
+	Timeout time.Duration
+
+	ids map[uint64]bool
+}
+
+// NewBaseline records the goroutines currently running
+func NewBaseline() *Baseline {
+	ids := make(map[uint64]bool)
+	for id := range goroutine.Stacks() {
+		ids[id] = true
+	}
+	return &Baseline{Timeout: time.Second, ids: ids}
+}
+
+// Check is a shortcut to verify that no goroutine leaks at the end of a test,
+// e.g.
+//
+//	defer runtest.Check(t)()
+func Check(t testing.TB) func() {
+	b := NewBaseline()
+	return func() {
+		t.Helper()
+		b.Verify(t)
+	}
+}
+
+// Verify waits for the goroutines started since the baseline to exit, and
+// fails the test with their stacks if any of them is still running after
+// Timeout.
+func (b *Baseline) Verify(t testing.TB) {
+	t.Helper()
+	if leaked := b.wait(); len(leaked) > 0 {
+		t.Errorf("%d goroutines leaked:\n\n%s", len(leaked), bytes.Join(leaked, []byte("\n\n")))
+	}
+}
+
+// Wait calls wait (e.g. the Wait method of a group or the Close method of a
+// pool) and waits for it to return within Timeout, so that every runner
+// has exited. It fails the test with the stacks of the goroutines started
+// since the baseline if wait does not return in time.
+func (b *Baseline) Wait(t testing.TB, wait func() error) error {
+	t.Helper()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- wait()
+	}()
+	timer := time.NewTimer(b.Timeout)
+	defer timer.Stop()
+	select {
+	case err := <-errChan:
+		return err
+	case <-timer.C:
+	}
+	leaked := b.leaked()
+	t.Errorf("runners not exited after %v, %d goroutines running:\n\n%s", b.Timeout, len(leaked), bytes.Join(leaked, []byte("\n\n")))
+	return nil
+}
+
+// wait polls until no goroutine leaks or the timeout expires, and returns
+// the stacks of the leaked goroutines
+func (b *Baseline) wait() [][]byte {
+	deadline := time.Now().Add(b.Timeout)
+	for delay := time.Microsecond; ; delay *= 2 {
+		leaked := b.leaked()
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		if max := 100 * time.Millisecond; delay > max {
+			delay = max
+		}
+		time.Sleep(delay)
+	}
+}
+
+// leaked returns the stacks of goroutines not in the baseline, excluding the
+// current goroutine
+func (b *Baseline) leaked() [][]byte {
+	self := goroutine.ID()
+	var ids []uint64
+	stacks := goroutine.Stacks()
+	for id := range stacks {
+		if !b.ids[id] && id != self {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	leaked := make([][]byte, len(ids))
+	for i, id := range ids {
+		leaked[i] = stacks[id]
+	}
+	return leaked
+}
@@ -0,0 +1,75 @@
+package runtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordT struct {
+	testing.TB
+	errs []string
+}
+
+func (t *recordT) Helper() {}
+
+func (t *recordT) Errorf(format string, args ...interface{}) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func TestBaselineNoLeak(t *testing.T) {
+	b := NewBaseline()
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(time.Millisecond)
+		close(done)
+	}()
+	<-done
+	rt := &recordT{TB: t}
+	b.Verify(rt)
+	if len(rt.errs) > 0 {
+		t.Fatalf("expect no leak but got %v", rt.errs)
+	}
+}
+
+func TestBaselineLeak(t *testing.T) {
+	b := NewBaseline()
+	b.Timeout = 10 * time.Millisecond
+	quitChan := make(chan struct{})
+	defer close(quitChan)
+	go leakingGoroutine(quitChan)
+
+	rt := &recordT{TB: t}
+	b.Verify(rt)
+	if len(rt.errs) != 1 {
+		t.Fatalf("expect leak reported but got %v", rt.errs)
+	}
+	if !strings.Contains(rt.errs[0], "leakingGoroutine") {
+		t.Fatalf("expect the stack of the leaked goroutine but got %s", rt.errs[0])
+	}
+}
+
+func TestBaselineWait(t *testing.T) {
+	b := NewBaseline()
+	b.Timeout = 10 * time.Millisecond
+	quitChan := make(chan struct{})
+	defer close(quitChan)
+	go leakingGoroutine(quitChan)
+
+	rt := &recordT{TB: t}
+	b.Wait(rt, func() error {
+		<-quitChan
+		return nil
+	})
+	if len(rt.errs) != 1 {
+		t.Fatalf("expect timeout reported but got %v", rt.errs)
+	}
+	if !strings.Contains(rt.errs[0], "leakingGoroutine") {
+		t.Fatalf("expect the stack of the running goroutine but got %s", rt.errs[0])
+	}
+}
+
+func leakingGoroutine(quitChan chan struct{}) {
+	<-quitChan
+}
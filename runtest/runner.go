@@ -0,0 +1,84 @@
+package runtest
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeRunner is a controllable runner for testing. The zero value returns nil
+// immediately.
+type FakeRunner struct {
+	Err   error       // error returned by Run
+	Panic interface{} // if not nil, Run panics with it instead of returning
+	Block bool        // if true, Run blocks until Release is called or ctx is cancelled
+
+	mu          sync.Mutex
+	calls       int
+	startChan   chan struct{}
+	releaseChan chan struct{}
+	releaseOnce sync.Once
+}
+
+// Run counts the invocation, blocks if Block is true, and then panics with
+// Panic or returns Err. If ctx is cancelled before being released, ctx.Err()
+// is returned.
+func (r *FakeRunner) Run(ctx context.Context) error {
+	r.mu.Lock()
+	r.calls++
+	if r.calls == 1 {
+		close(r.started())
+	}
+	releaseChan := r.released()
+	r.mu.Unlock()
+
+	if r.Block {
+		select {
+		case <-releaseChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if r.Panic != nil {
+		panic(r.Panic)
+	}
+	return r.Err
+}
+
+// Calls returns the number of times that Run has been called
+func (r *FakeRunner) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+// Started returns a channel that is closed when Run is called for the first
+// time
+func (r *FakeRunner) Started() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.started()
+}
+
+// Release unblocks all the current and subsequent calls to Run
+func (r *FakeRunner) Release() {
+	r.mu.Lock()
+	releaseChan := r.released()
+	r.mu.Unlock()
+	r.releaseOnce.Do(func() {
+		close(releaseChan)
+	})
+}
+
+func (r *FakeRunner) started() chan struct{} {
+	if r.startChan == nil {
+		r.startChan = make(chan struct{})
+	}
+	return r.startChan
+}
+
+func (r *FakeRunner) released() chan struct{} {
+	if r.releaseChan == nil {
+		r.releaseChan = make(chan struct{})
+	}
+	return r.releaseChan
+}
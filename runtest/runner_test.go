@@ -0,0 +1,61 @@
+package runtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeRunnerErr(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	r := &FakeRunner{Err: errRun}
+	for i := 0; i < 2; i++ {
+		if err := r.Run(context.Background()); err != errRun {
+			t.Fatalf("expect error %v got %v", errRun, err)
+		}
+	}
+	if r.Calls() != 2 {
+		t.Fatalf("expect 2 calls but got %d", r.Calls())
+	}
+}
+
+func TestFakeRunnerBlock(t *testing.T) {
+	t.Parallel()
+
+	r := &FakeRunner{Block: true}
+	errChan := make(chan error)
+	go func() {
+		errChan <- r.Run(context.Background())
+	}()
+	<-r.Started()
+	select {
+	case err := <-errChan:
+		t.Fatalf("expect blocking but returned %v", err)
+	default:
+	}
+	r.Release()
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = &FakeRunner{Block: true}
+	if err := r.Run(ctx); err != context.Canceled {
+		t.Fatalf("expect error %v got %v", context.Canceled, err)
+	}
+}
+
+func TestFakeRunnerPanic(t *testing.T) {
+	t.Parallel()
+
+	r := &FakeRunner{Panic: "test panic"}
+	defer func() {
+		if v := recover(); v != "test panic" {
+			t.Fatalf("expect panic %v got %v", "test panic", v)
+		}
+	}()
+	r.Run(context.Background())
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"h12.io/run"
+	"h12.io/run/internal/ctxutil"
+)
+
+// HTTP adapts an http.Server into a runner, which is also a run.Readier and a
+// run.Stopper.
+//
+// If a pool is specified by the Pool option or run.PoolFromContext, the
+// handler of each request is run on the pool, so that the limits of the pool
+// apply to the requests, and a request failing to be dispatched is replied
+// with 503 Service Unavailable. A panic of the handler is propagated back to
+// net/http as usual.
+type HTTP struct {
+	server   *http.Server
+	listener net.Listener
+	options  options
+
+	ready     chan struct{}
+	readyOnce sync.Once
+	drained   chan struct{}
+	drainOnce sync.Once
+	poolOnce  sync.Once
+	conns     int64
+}
+
+// NewHTTP creates a new HTTP runner for server. If listener is nil, the
+// runner listens on the TCP network address server.Addr when it runs.
+//
+// The ConnState hook of server is wrapped to count the connections.
+func NewHTTP(server *http.Server, listener net.Listener, options ...Option) *HTTP {
+	h := &HTTP{
+		server:   server,
+		listener: listener,
+		options:  newOptions(options),
+		ready:    make(chan struct{}),
+		drained:  make(chan struct{}),
+	}
+	connState := server.ConnState
+	server.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			atomic.AddInt64(&h.conns, 1)
+		case http.StateHijacked, http.StateClosed:
+			atomic.AddInt64(&h.conns, -1)
+		}
+		if connState != nil {
+			connState(conn, state)
+		}
+	}
+	return h
+}
+
+// Name returns the name of the runner with its address
+func (h *HTTP) Name() string {
+	return "http " + h.server.Addr
+}
+
+// Ready returns a channel that is closed once the server is listening
+func (h *HTTP) Ready() <-chan struct{} {
+	return h.ready
+}
+
+// Addr returns the address that the server is listening on, it is only valid
+// after it is ready
+func (h *HTTP) Addr() net.Addr {
+	<-h.ready
+	return h.listener.Addr()
+}
+
+// Conns returns the number of the connections currently open
+func (h *HTTP) Conns() int {
+	return int(atomic.LoadInt64(&h.conns))
+}
+
+// Run serves HTTP until ctx is cancelled or Stop is called. On cancellation,
+// the server is shut down gracefully within the grace period, and then closed
+// forcibly. It returns nil if the server is shut down or closed, otherwise
+// the error of listening or serving.
+func (h *HTTP) Run(ctx context.Context) error {
+	if h.listener == nil {
+		addr := h.server.Addr
+		if addr == "" {
+			addr = ":http"
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		h.listener = listener
+	}
+	pool := h.options.pool
+	if pool == nil {
+		pool = run.PoolFromContext(ctx)
+	}
+	if pool != nil {
+		h.poolOnce.Do(func() {
+			handler := h.server.Handler
+			if handler == nil {
+				handler = http.DefaultServeMux
+			}
+			h.server.Handler = poolHandler{handler: handler, pool: pool}
+		})
+	}
+	h.readyOnce.Do(func() { close(h.ready) })
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- h.server.Serve(h.listener)
+	}()
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		// Stop is called, wait for the in-flight requests
+		select {
+		case <-h.drained:
+			return nil
+		case <-ctx.Done():
+		}
+	case <-ctx.Done():
+		defer func() { <-serveErr }()
+	}
+
+	clock := run.ClockFromContext(ctx)
+	graceCtx, cancel := context.WithCancel(ctxutil.Detach(ctx))
+	defer cancel()
+	timer := clock.AfterFunc(h.options.grace, cancel)
+	defer timer.Stop()
+	if err := h.server.Shutdown(graceCtx); err != nil {
+		h.server.Close()
+	}
+	return nil
+}
+
+// Stop stops the server from accepting new connections and waits for the
+// in-flight requests to finish, after which Run returns nil. If ctx is done
+// first, ctx.Err() is returned and the remaining connections are closed by
+// the cancellation of the runner after the grace period.
+func (h *HTTP) Stop(ctx context.Context) error {
+	if err := h.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	h.drainOnce.Do(func() { close(h.drained) })
+	return nil
+}
+
+// poolHandler runs the handler of each request on a pool
+type poolHandler struct {
+	handler http.Handler
+	pool    run.Pool
+}
+
+func (h poolHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	done := make(chan interface{}, 1)
+	if err := h.pool.Go(r.Context(), func() {
+		defer func() { done <- recover() }()
+		h.handler.ServeHTTP(w, r)
+	}); err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	if v := <-done; v != nil {
+		// handled by net/http on the goroutine of the connection
+		panic(v)
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/gopool"
+)
+
+func TestHTTPGracefulStop(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := NewHTTP(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})}, listen(t))
+	h := run.Start(context.Background(), srv)
+	<-h.Ready()
+
+	respChan := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + srv.Addr().String())
+		if err != nil {
+			t.Error(err)
+			respChan <- ""
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		respChan <- string(body)
+	}()
+	<-started
+	if n := srv.Conns(); n != 1 {
+		t.Fatalf("expect 1 connection but got %d", n)
+	}
+
+	stopErr := make(chan error)
+	go func() { stopErr <- h.Stop(context.Background()) }()
+	select {
+	case <-h.Done():
+		t.Fatal("expect the server to wait for the in-flight request")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if body := <-respChan; body != "done" {
+		t.Fatalf("expect response %q but got %q", "done", body)
+	}
+	if err := <-stopErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPGrace(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	srv := NewHTTP(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}, listen(t), Grace(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	h := run.Start(ctx, srv)
+	<-h.Ready()
+	go http.Get("http://" + srv.Addr().String())
+	<-started
+	cancel()
+	if err := h.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPPool(t *testing.T) {
+	t.Parallel()
+
+	pool := gopool.NewGoroutinePool()
+	defer pool.Close()
+	ran := make(chan bool, 1)
+	srv := NewHTTP(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran <- true
+		w.Write([]byte("done"))
+	})}, listen(t), Pool(pool))
+	ctx, cancel := context.WithCancel(context.Background())
+	h := run.Start(ctx, srv)
+	<-h.Ready()
+	if code := get(t, srv); code != http.StatusOK {
+		t.Fatalf("expect status %d but got %d", http.StatusOK, code)
+	}
+	<-ran
+
+	// a closed pool rejects the requests
+	pool.Close()
+	if code := get(t, srv); code != http.StatusServiceUnavailable {
+		t.Fatalf("expect status %d but got %d", http.StatusServiceUnavailable, code)
+	}
+	cancel()
+	if err := h.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(t *testing.T, srv *HTTP) int {
+	t.Helper()
+	resp, err := http.Get("http://" + srv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	return resp.StatusCode
+}
+
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
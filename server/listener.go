@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/gopool"
+	"h12.io/run/internal/ctxutil"
+)
+
+// Handler handles a connection accepted by Listener, the connection is closed
+// after it returns. ctx is cancelled when the grace period elapses after the
+// runner is cancelled.
+type Handler func(ctx context.Context, conn net.Conn) error
+
+// Listener adapts an accept loop of a net.Listener into a runner, which is
+// also a run.Readier and a run.Stopper. Each accepted connection is handled by
+// its handler on a goroutine pool, with panics recovered.
+type Listener struct {
+	listener net.Listener
+	handler  Handler
+	options  options
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	drained  chan struct{}
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// NewListener creates a new Listener accepting connections from listener
+func NewListener(listener net.Listener, handler Handler, options ...Option) *Listener {
+	return &Listener{
+		listener: listener,
+		handler:  handler,
+		options:  newOptions(options),
+		stopChan: make(chan struct{}),
+		drained:  make(chan struct{}),
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// Name returns the name of the runner with its address
+func (l *Listener) Name() string {
+	return "listener " + l.listener.Addr().String()
+}
+
+// Ready returns a closed channel, because the listener is already listening
+func (l *Listener) Ready() <-chan struct{} {
+	return closedChan
+}
+
+// Conns returns the number of the connections being handled
+func (l *Listener) Conns() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+// Run accepts connections until ctx is cancelled or Stop is called, and
+// dispatches them to the handler. On cancellation, the in-flight connections
+// are given the grace period to finish, and then their contexts are cancelled
+// and they are closed forcibly. Run returns after all the handlers exit, with
+// nil or the error that fails the accept loop.
+func (l *Listener) Run(ctx context.Context) error {
+	defer close(l.drained)
+	pool := l.options.pool
+	if pool == nil {
+		pool = run.PoolFromContext(ctx)
+	}
+	connCtx, cancelConns := context.WithCancel(ctxutil.Detach(ctx))
+	defer cancelConns()
+
+	// dispatchCtx is cancelled once the listener stops accepting, so that
+	// dispatching onto a busy pool does not block the stopping
+	dispatchCtx, cancelDispatch := context.WithCancel(ctx)
+	defer cancelDispatch()
+	go func() {
+		select {
+		case <-dispatchCtx.Done():
+		case <-l.stopChan:
+		}
+		cancelDispatch()
+		l.listener.Close()
+	}()
+
+	err := l.accept(connCtx, dispatchCtx, pool)
+	cancelDispatch()
+	if ctx.Err() != nil || l.stopped() {
+		err = nil
+	}
+
+	handlersDone := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(handlersDone)
+	}()
+	select {
+	case <-handlersDone:
+		return err
+	case <-ctx.Done():
+	}
+	timer := run.ClockFromContext(ctx).NewTimer(l.options.grace)
+	defer timer.Stop()
+	select {
+	case <-handlersDone:
+		return err
+	case <-timer.C():
+	}
+	cancelConns()
+	l.mu.Lock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+	<-handlersDone
+	return err
+}
+
+// accept runs the accept loop until the listener fails or is closed
+func (l *Listener) accept(ctx, dispatchCtx context.Context, pool run.Pool) error {
+	var delay time.Duration
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if temporary(err) {
+				// back off in the manner of net/http
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		l.mu.Lock()
+		l.conns[conn] = struct{}{}
+		l.mu.Unlock()
+		l.wg.Add(1)
+		fn := func() { l.serve(ctx, conn) }
+		if pool == nil {
+			go fn()
+		} else if err := pool.Go(dispatchCtx, fn); err != nil {
+			l.done(conn)
+			l.options.onError(conn, err)
+		}
+	}
+}
+
+// temporary returns if an accept error is worth retrying, e.g. running out of
+// file descriptors
+func temporary(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EMFILE) ||
+		errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ECONNABORTED)
+}
+
+// serve runs the handler and recovers its panic
+func (l *Listener) serve(ctx context.Context, conn net.Conn) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = gopool.NewPanicError(r)
+		}
+		if err != nil {
+			l.options.onError(conn, err)
+		}
+		l.done(conn)
+	}()
+	err = l.handler(ctx, conn)
+}
+
+// done closes conn and stops tracking it
+func (l *Listener) done(conn net.Conn) {
+	conn.Close()
+	l.mu.Lock()
+	delete(l.conns, conn)
+	l.mu.Unlock()
+	l.wg.Done()
+}
+
+func (l *Listener) stopped() bool {
+	select {
+	case <-l.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops accepting new connections and waits for the in-flight ones to
+// finish, after which Run returns. If ctx is done first, ctx.Err() is
+// returned and the remaining connections are closed by the cancellation of
+// the runner after the grace period.
+func (l *Listener) Stop(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stopChan) })
+	select {
+	case <-l.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/gopool"
+)
+
+func TestListener(t *testing.T) {
+	t.Parallel()
+
+	pool := gopool.NewGoroutinePool(gopool.Max(2))
+	defer pool.Close()
+	errChan := make(chan error, 1)
+	l := NewListener(listen(t), func(ctx context.Context, conn net.Conn) error {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if line == "panic\n" {
+			panic("handler panics")
+		}
+		_, err = conn.Write([]byte(line))
+		return err
+	}, Pool(pool), OnError(func(conn net.Conn, err error) {
+		errChan <- err
+	}))
+	h := run.Start(context.Background(), l)
+
+	conn := dial(t, l)
+	conn.Write([]byte("hello\n"))
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err != nil || line != "hello\n" {
+		t.Fatalf("expect echo %q but got %q, %v", "hello\n", line, err)
+	}
+	conn.Close()
+
+	conn = dial(t, l)
+	conn.Write([]byte("panic\n"))
+	var panicErr *gopool.PanicError
+	if err := <-errChan; !errors.As(err, &panicErr) {
+		t.Fatalf("expect a panic error but got %v", err)
+	}
+	conn.Close()
+
+	if err := h.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := l.Conns(); n != 0 {
+		t.Fatalf("expect no connections but got %d", n)
+	}
+}
+
+func TestListenerGrace(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	l := NewListener(listen(t), func(ctx context.Context, conn net.Conn) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	}, Grace(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	h := run.Start(ctx, l)
+	conn := dial(t, l)
+	defer conn.Close()
+	<-started
+	cancel()
+	if err := h.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dial(t *testing.T, l *Listener) net.Conn {
+	conn, err := net.Dial("tcp", l.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
@@ -0,0 +1,69 @@
+// Package server provides runners serving network connections, which are
+// ready once listening and shut down gracefully on cancellation.
+package server
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"h12.io/run"
+)
+
+// Option is used to specify an option for HTTP and Listener
+type Option func(*options)
+
+type options struct {
+	grace   time.Duration
+	pool    run.Pool
+	onError func(conn net.Conn, err error)
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		grace: 30 * time.Second,
+		onError: func(conn net.Conn, err error) {
+			log.Printf("server: connection from %s: %v", conn.RemoteAddr(), err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Grace returns the option to specify the maximum time for the in-flight
+// connections to finish after the context of the runner is cancelled, if not
+// specified, the default grace period is 30s
+func Grace(grace time.Duration) Option {
+	return func(o *options) {
+		o.grace = grace
+	}
+}
+
+// Pool returns the option to specify the goroutine pool, e.g.
+// gopool.GoroutinePool, on which Listener runs the connection handlers and
+// HTTP runs the request handlers, if not specified, the pool from
+// run.PoolFromContext is used, or new goroutines are started if there is none
+func Pool(pool run.Pool) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// OnError returns the option to specify the function called with the error
+// returned by a connection handler of Listener, including a recovered panic
+// as *gopool.PanicError, if not specified, the error is logged by the
+// standard logger
+func OnError(f func(conn net.Conn, err error)) Option {
+	return func(o *options) {
+		o.onError = f
+	}
+}
+
+// closedChan is returned by Listener.Ready
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
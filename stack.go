@@ -0,0 +1,143 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Stack is an ordered shutdown stack: the closers and cleanup runners
+// registered during startup are closed or run in LIFO order on Close, in the
+// manner of defer statements, e.g.
+//
+//	stack := run.NewStack(run.ShutdownTimeout(10 * time.Second))
+//	defer stack.Close()
+//	db, err := sql.Open(driver, dsn)
+//	...
+//	stack.Push(db)
+//	stack.Start(ctx, server)
+type Stack struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	items  []interface{} // io.Closer or cleanupRunner
+	closed bool
+}
+
+// StackOption is used to specify an option for Stack
+type StackOption func(*Stack)
+
+// ShutdownTimeout returns the option to specify the maximum time for Close to
+// run all the cleanups, if not specified, the default timeout is 30s
+func ShutdownTimeout(timeout time.Duration) StackOption {
+	if timeout <= 0 {
+		panic("shutdown timeout should always be positive")
+	}
+	return func(s *Stack) {
+		s.timeout = timeout
+	}
+}
+
+// NewStack creates a new Stack based on the options provided
+func NewStack(options ...StackOption) *Stack {
+	s := &Stack{timeout: 30 * time.Second}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Push registers closer to be closed on Close. If closer is also a Stopper,
+// e.g. a Handle, Stop is called instead with the context of the shutdown.
+func (s *Stack) Push(closer io.Closer) {
+	s.push(closer)
+}
+
+// Defer registers cleanup to be run on Close with the context of the
+// shutdown, which is not cancelled until the shutdown timeout elapses
+func (s *Stack) Defer(cleanup Runner) {
+	s.push(cleanupRunner{cleanup})
+}
+
+// cleanupRunner wraps a cleanup runner, so that it is run rather than
+// stopped even if it is a Stopper
+type cleanupRunner struct {
+	runner Runner
+}
+
+// Name returns the name of the cleanup runner
+func (r cleanupRunner) Name() string {
+	return Name(r.runner)
+}
+
+// Start starts runner by Start, and registers its handle to be stopped on
+// Close
+func (s *Stack) Start(ctx context.Context, runner Runner, options ...StartOption) *Handle {
+	h := Start(ctx, runner, options...)
+	s.push(h)
+	return h
+}
+
+func (s *Stack) push(item interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		panic("push to a closed stack")
+	}
+	s.items = append(s.items, item)
+}
+
+// Close closes or runs the registered cleanups in LIFO order with a fresh
+// context, which is cancelled when the shutdown timeout elapses. An io.Closer
+// not returning before the timeout is left closing in the background, and the
+// remaining io.Closers are still closed but not waited for.
+//
+// All the errors are returned as Errors, each wrapped with the name of the
+// cleanup. Subsequent calls to Close will return ErrClosed.
+func (s *Stack) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	items := s.items
+	s.items = nil
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	var errs Errors
+	for i := len(items) - 1; i >= 0; i-- {
+		if err := cleanup(ctx, items[i]); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", Name(items[i]), err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func cleanup(ctx context.Context, item interface{}) error {
+	switch item := item.(type) {
+	case cleanupRunner:
+		return item.runner.Run(ctx)
+	case Stopper:
+		return item.Stop(ctx)
+	case io.Closer:
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- item.Close()
+		}()
+		select {
+		case err := <-errChan:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
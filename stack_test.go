@@ -0,0 +1,110 @@
+package run_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"h12.io/run"
+	"h12.io/run/runtest"
+)
+
+func TestStack(t *testing.T) {
+	defer runtest.Check(t)()
+
+	errClose := errors.New("err close")
+	var order []string
+	stack := run.NewStack()
+	stack.Push(closerFunc(func() error {
+		order = append(order, "closer")
+		return errClose
+	}))
+	stack.Defer(run.Func(func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			t.Errorf("expect a fresh context but got %v", ctx.Err())
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expect a deadline of the shutdown")
+		}
+		order = append(order, "cleanup")
+		return nil
+	}))
+	h := stack.Start(context.Background(), run.Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		order = append(order, "runner")
+		return nil
+	}))
+
+	err := stack.Close()
+	if want := []string{"runner", "cleanup", "closer"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("expect order %v got %v", want, order)
+	}
+	var errs run.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(err, errClose) {
+		t.Fatalf("expect error %v got %v", errClose, err)
+	}
+	if err := h.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stack.Close(); err != run.ErrClosed {
+		t.Fatalf("expect error %v got %v", run.ErrClosed, err)
+	}
+}
+
+func TestStackTimeout(t *testing.T) {
+	defer runtest.Check(t)()
+
+	release := make(chan struct{})
+	closed := make(chan struct{})
+	stack := run.NewStack(run.ShutdownTimeout(10 * time.Millisecond))
+	stack.Push(closerFunc(func() error {
+		close(closed)
+		return nil
+	}))
+	stack.Push(closerFunc(func() error {
+		<-release // hangs
+		return nil
+	}))
+	if err := stack.Close(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect error %v got %v", context.DeadlineExceeded, err)
+	}
+	// the closers after the hanging one are still closed
+	<-closed
+	close(release)
+}
+
+func TestStackDeferCloser(t *testing.T) {
+	defer runtest.Check(t)()
+
+	cleanup := &closingRunner{}
+	stack := run.NewStack()
+	stack.Defer(cleanup)
+	if err := stack.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !cleanup.ran || cleanup.closed {
+		t.Fatalf("expect the deferred runner run but got ran %v, closed %v", cleanup.ran, cleanup.closed)
+	}
+}
+
+// closingRunner is a Runner that is also an io.Closer
+type closingRunner struct {
+	ran    bool
+	closed bool
+}
+
+func (r *closingRunner) Run(ctx context.Context) error {
+	r.ran = true
+	return nil
+}
+
+func (r *closingRunner) Close() error {
+	r.closed = true
+	return nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
@@ -0,0 +1,78 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned by a runner wrapped by WithTimeout or WithDeadline
+// when the runner fails after its own deadline is exceeded, which is
+// distinguishable from the cancellation of the parent context
+type TimeoutError struct {
+	Runner   Runner
+	Deadline time.Time
+	Err      error // error returned by the runner
+}
+
+// Error satisfies error interface
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out at %s: %v", Name(e.Runner), e.Deadline.Format(time.RFC3339Nano), e.Err)
+}
+
+// Unwrap returns the error returned by the runner
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// Timeout always returns true, in the manner of net.Error
+func (e *TimeoutError) Timeout() bool { return true }
+
+// WithTimeout returns a runner that runs runner with its own context, which is
+// cancelled when the timeout elapses since each run starts. The timeout is
+// measured by the clock from ClockFromContext.
+func WithTimeout(runner Runner, timeout time.Duration) Runner {
+	return &deadlineRunner{runner: runner, timeout: timeout, relative: true}
+}
+
+// WithDeadline returns a runner that runs runner with its own context, which is
+// cancelled when the deadline expires. The deadline is measured by the clock
+// from ClockFromContext.
+func WithDeadline(runner Runner, deadline time.Time) Runner {
+	return &deadlineRunner{runner: runner, deadline: deadline}
+}
+
+type deadlineRunner struct {
+	runner   Runner
+	timeout  time.Duration
+	deadline time.Time
+	relative bool
+}
+
+// Name returns the name of the underlying runner
+func (r *deadlineRunner) Name() string {
+	return Name(r.runner)
+}
+
+// Admit forwards to the underlying runner if it is an Admitter
+func (r *deadlineRunner) Admit(ctx context.Context) error {
+	if a, ok := r.runner.(Admitter); ok {
+		return a.Admit(ctx)
+	}
+	return nil
+}
+
+// Run runs the underlying runner and returns a *TimeoutError if the runner
+// returns an error after its own deadline is exceeded
+func (r *deadlineRunner) Run(ctx context.Context) error {
+	clock := ClockFromContext(ctx)
+	deadline := r.deadline
+	if r.relative {
+		deadline = clock.Now().Add(r.timeout)
+	}
+	runCtx, cancel := withDeadline(ctx, clock, deadline)
+	defer cancel()
+	err := r.runner.Run(runCtx)
+	if err != nil && runCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return &TimeoutError{Runner: r.runner, Deadline: deadline, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	runner := WithTimeout(Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), time.Millisecond)
+	err := runner.Run(context.Background())
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expect *TimeoutError got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect the error of the runner is wrapped but got %v", timeoutErr.Err)
+	}
+}
+
+func TestWithTimeoutParentCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	runner := WithTimeout(Func(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), time.Hour)
+	if err := runner.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect error %v got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	t.Parallel()
+
+	errRun := errors.New("err run")
+	testcases := []struct {
+		name     string
+		deadline time.Time
+		runner   Runner
+		isErr    func(error) bool
+	}{
+		{
+			name:     "before deadline",
+			deadline: time.Now().Add(time.Hour),
+			runner:   Func(func(context.Context) error { return errRun }),
+			isErr:    func(err error) bool { return err == errRun },
+		},
+		{
+			name:     "after deadline",
+			deadline: time.Now().Add(-time.Second),
+			runner:   Func(func(ctx context.Context) error { return ctx.Err() }),
+			isErr: func(err error) bool {
+				var timeoutErr *TimeoutError
+				return errors.As(err, &timeoutErr)
+			},
+		},
+		{
+			name:     "success after deadline",
+			deadline: time.Now().Add(-time.Second),
+			runner:   Func(func(context.Context) error { return nil }),
+			isErr:    func(err error) bool { return err == nil },
+		},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if err := WithDeadline(tc.runner, tc.deadline).Run(context.Background()); !tc.isErr(err) {
+				t.Fatalf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestWithTimeoutAdmit(t *testing.T) {
+	t.Parallel()
+
+	errReject := errors.New("reject")
+	runner := WithTimeout(admitter{err: errReject}, time.Hour)
+	if err := runner.(Admitter).Admit(context.Background()); err != errReject {
+		t.Fatalf("expect %v got %v", errReject, err)
+	}
+	runner = WithTimeout(Func(func(context.Context) error { return nil }), time.Hour)
+	if err := runner.(Admitter).Admit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type admitter struct {
+	err error
+}
+
+func (a admitter) Run(ctx context.Context) error   { return nil }
+func (a admitter) Admit(ctx context.Context) error { return a.err }